@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// AlertEvent identifies what happened to the timer
+type AlertEvent string
+
+const (
+	EventSessionFinished AlertEvent = "session_finished"
+	EventSessionWarning  AlertEvent = "session_warning" // A configured time before the end
+	EventMeetingAhead    AlertEvent = "meeting_ahead"   // A calendar meeting cuts into a work session
+)
+
+// Alert carries everything a notifier needs to tell the user
+type Alert struct {
+	Event   AlertEvent
+	Session SessionSlot
+	Title   string
+	Message string
+	Actions []NotificationAction // Only used by notifiers with buttons
+}
+
+// Notifier delivers an alert one particular way (notification, dialog, sound...)
+type Notifier interface {
+	Name() string
+	Notify(alert Alert) error
+}
+
+// AlertRoute says which notifiers fire for an event and session type
+type AlertRoute struct {
+	Event       AlertEvent  `json:"event"`
+	SessionType SessionType `json:"session_type,omitempty"` // Empty matches any session
+	Notifiers   []string    `json:"notifiers"`
+}
+
+// defaultAlertRoutes keeps the original "everything, every time" behaviour
+var defaultAlertRoutes = []AlertRoute{
+	{Event: EventSessionFinished, Notifiers: []string{"desktop", "dialog", "focus"}},
+	{Event: EventSessionWarning, Notifiers: []string{"desktop"}},
+	{Event: EventMeetingAhead, Notifiers: []string{"desktop"}},
+}
+
+// alertCommandTimeout stops a hung command notifier from piling up
+const alertCommandTimeout = 10 * time.Second
+
+// Built-in notifiers, looked up by the names used in AlertRoute
+var builtinNotifiers = map[string]Notifier{
+	"desktop": desktopAlertNotifier{},
+	"dialog":  dialogAlertNotifier{},
+	"focus":   focusAlertNotifier{},
+	"sound":   soundAlertNotifier{},
+	"bell":    bellAlertNotifier{},
+	"command": commandAlertNotifier{},
+}
+
+// alertRoutes returns the configured routes, falling back to the defaults
+func alertRoutes() []AlertRoute {
+	if len(DefaultSettings.AlertRoutes) == 0 {
+		return defaultAlertRoutes
+	}
+	return DefaultSettings.AlertRoutes
+}
+
+// notifiersFor picks the notifiers for an alert.
+// A route for the exact session type beats a catch-all route for the event.
+func notifiersFor(alert Alert, routes []AlertRoute) []string {
+	var fallback []string
+	for _, route := range routes {
+		if route.Event != alert.Event {
+			continue
+		}
+		if route.SessionType == alert.Session.Type {
+			return route.Notifiers
+		}
+		if route.SessionType == "" && fallback == nil {
+			fallback = route.Notifiers
+		}
+	}
+	return fallback
+}
+
+// dispatchAlert fires every routed notifier, logging the ones that fail
+func dispatchAlert(alert Alert, routes []AlertRoute, registry map[string]Notifier) {
+	for _, name := range notifiersFor(alert, routes) {
+		notifier, ok := registry[name]
+		if !ok {
+			log.Printf("alerts: unknown notifier %q", name)
+			continue
+		}
+		if err := notifier.Notify(alert); err != nil {
+			log.Printf("alerts: %s notifier failed: %v", notifier.Name(), err)
+		}
+	}
+}
+
+// triggerSessionAlerts tells the user the current session is over
+func triggerSessionAlerts() {
+	if sessionManager == nil {
+		return
+	}
+	current := sessionManager.GetCurrentSession()
+	if current == nil {
+		return
+	}
+
+	nextUp := "Back to work, ye scallywag!"
+	if current.Type == SessionWork {
+		nextUp = "Time to take a break and stretch yer sea legs!"
+	}
+
+	alert := Alert{
+		Event:   EventSessionFinished,
+		Session: *current,
+		Title:   "🏴‍☠️ GoModoro Complete!",
+		Message: fmt.Sprintf("Avast! %s be finished!\n\n%s", current.GetSessionLabel(), nextUp),
+		Actions: sessionFinishedActions(),
+	}
+	dispatchAlert(alert, alertRoutes(), builtinNotifiers)
+}
+
+// desktopAlertNotifier shows a desktop notification with action buttons
+type desktopAlertNotifier struct{}
+
+func (desktopAlertNotifier) Name() string { return "desktop" }
+
+func (desktopAlertNotifier) Notify(alert Alert) error {
+	if desktopNotifier == nil {
+		return errNoNotificationDaemon
+	}
+	return desktopNotifier.Notify(alert.Title, alert.Message, alert.Actions)
+}
+
+// dialogAlertNotifier pops up a modal dialog that must be clicked
+type dialogAlertNotifier struct{}
+
+func (dialogAlertNotifier) Name() string { return "dialog" }
+
+func (dialogAlertNotifier) Notify(alert Alert) error {
+	// Pop-up dialog (must run on UI thread)
+	fyne.Do(func() {
+		showAnnoyingPopup(alert.Title, alert.Message)
+	})
+	return nil
+}
+
+// focusAlertNotifier brings the main window to the front
+type focusAlertNotifier struct{}
+
+func (focusAlertNotifier) Name() string { return "focus" }
+
+func (focusAlertNotifier) Notify(alert Alert) error {
+	// Might be hidden in the tray, or swapped for the mini window
+	fyne.Do(showFullWindow)
+	return nil
+}
+
+// soundAlertNotifier plays the configured finish or warning sound
+type soundAlertNotifier struct{}
+
+func (soundAlertNotifier) Name() string { return "sound" }
+
+func (soundAlertNotifier) Notify(alert Alert) error {
+	if alert.Event == EventSessionWarning {
+		return playSoundCue(DefaultSettings.Sounds.Warning)
+	}
+	return playSoundCue(DefaultSettings.Sounds.Finish)
+}
+
+// bellAlertNotifier rings the terminal bell
+type bellAlertNotifier struct{}
+
+func (bellAlertNotifier) Name() string { return "bell" }
+
+func (bellAlertNotifier) Notify(alert Alert) error {
+	// Prefer the controlling terminal, stdout may be redirected
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		_, err = tty.WriteString("\a")
+		return err
+	}
+	_, err := os.Stdout.WriteString("\a")
+	return err
+}
+
+// commandAlertNotifier runs the user's AlertCommand with the alert in its environment
+type commandAlertNotifier struct{}
+
+func (commandAlertNotifier) Name() string { return "command" }
+
+func (commandAlertNotifier) Notify(alert Alert) error {
+	if DefaultSettings.AlertCommand == "" {
+		return fmt.Errorf("no AlertCommand configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), alertCommandTimeout)
+	cmd := exec.CommandContext(ctx, "sh", "-c", DefaultSettings.AlertCommand)
+	cmd.Env = append(os.Environ(),
+		"GOMODORO_EVENT="+string(alert.Event),
+		"GOMODORO_SESSION_TYPE="+string(alert.Session.Type),
+		"GOMODORO_TITLE="+alert.Title,
+		"GOMODORO_MESSAGE="+alert.Message,
+	)
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return err
+	}
+
+	// Don't hold up the timer waiting for the command
+	go func() {
+		defer cancel()
+		if err := cmd.Wait(); err != nil {
+			log.Printf("alerts: command notifier failed: %v", err)
+		}
+	}()
+	return nil
+}
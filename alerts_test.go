@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// recordingNotifier remembers the alerts it was asked to deliver
+type recordingNotifier struct {
+	name   string
+	alerts []Alert
+	err    error
+}
+
+func (r *recordingNotifier) Name() string { return r.name }
+
+func (r *recordingNotifier) Notify(alert Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return r.err
+}
+
+func TestNotifiersForPrefersSessionType(t *testing.T) {
+	routes := []AlertRoute{
+		{Event: EventSessionFinished, Notifiers: []string{"desktop"}},
+		{Event: EventSessionFinished, SessionType: SessionWork, Notifiers: []string{"dialog", "sound"}},
+	}
+
+	tests := []struct {
+		name        string
+		sessionType SessionType
+		expected    []string
+	}{
+		{"Work uses its own route", SessionWork, []string{"dialog", "sound"}},
+		{"Break falls back to catch-all", SessionShortBreak, []string{"desktop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := Alert{Event: EventSessionFinished, Session: SessionSlot{Type: tt.sessionType}}
+			got := notifiersFor(alert, routes)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+
+	// No route for the event means no notifiers
+	if got := notifiersFor(Alert{Event: "other"}, routes); len(got) != 0 {
+		t.Errorf("Expected no notifiers for unrouted event, got %v", got)
+	}
+}
+
+func TestDispatchAlertKeepsGoingAfterFailure(t *testing.T) {
+	failing := &recordingNotifier{name: "failing", err: errors.New("boom")}
+	working := &recordingNotifier{name: "working"}
+	registry := map[string]Notifier{"failing": failing, "working": working}
+
+	routes := []AlertRoute{
+		{Event: EventSessionFinished, Notifiers: []string{"failing", "missing", "working"}},
+	}
+	dispatchAlert(Alert{Event: EventSessionFinished}, routes, registry)
+
+	if len(failing.alerts) != 1 || len(working.alerts) != 1 {
+		t.Errorf("Every routed notifier should fire, got failing=%d working=%d",
+			len(failing.alerts), len(working.alerts))
+	}
+}
+
+func TestAlertRoutesDefault(t *testing.T) {
+	originalSettings := DefaultSettings
+	defer func() {
+		DefaultSettings = originalSettings
+	}()
+
+	// State files from before routing existed have no routes
+	DefaultSettings.AlertRoutes = nil
+	if len(alertRoutes()) != len(defaultAlertRoutes) {
+		t.Error("Missing routes should fall back to the defaults")
+	}
+}
@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the calendar files are re-read
+const calendarRefreshInterval = 5 * time.Minute
+
+// MeetingPolicy is what happens when a work session would run into a meeting
+type MeetingPolicy string
+
+const (
+	MeetingWarn    MeetingPolicy = "warn"    // Just give a heads-up
+	MeetingShorten MeetingPolicy = "shorten" // End the work session when the meeting starts
+	MeetingSkip    MeetingPolicy = "skip"    // Go straight to a break until the meeting's over
+)
+
+// CalendarSettings points at .ics files (or directories of them) with busy time
+type CalendarSettings struct {
+	Paths          []string      `json:"paths"`
+	LookaheadHours int           `json:"lookahead_hours"`  // How far ahead to look for meetings
+	OnOverlap      MeetingPolicy `json:"on_overlap"`       // warn, shorten or skip
+	MinWorkMinutes int           `json:"min_work_minutes"` // Shortening below this skips instead
+	ExportICS      string        `json:"export_ics"`       // Keep finished focus sessions in this .ics file
+}
+
+// DefaultCalendarSettings - no calendars, just warn once some are added
+var DefaultCalendarSettings = CalendarSettings{
+	LookaheadHours: 12,
+	OnOverlap:      MeetingWarn,
+	MinWorkMinutes: 10,
+}
+
+// BusyBlock is a meeting (or anything else marked busy) from a calendar
+type BusyBlock struct {
+	Start   time.Time
+	End     time.Time
+	Summary string
+}
+
+// Cached busy blocks, refreshed in the background
+var (
+	calendarMu     sync.Mutex
+	calendarBlocks []BusyBlock
+)
+
+// setupCalendar keeps the busy blocks from the configured calendars fresh
+func setupCalendar() {
+	go func() {
+		for {
+			refreshCalendar()
+			time.Sleep(calendarRefreshInterval)
+		}
+	}()
+}
+
+// refreshCalendar re-reads every configured calendar
+func refreshCalendar() {
+	settings := DefaultSettings.Calendar
+	now := time.Now()
+	blocks := loadBusyBlocks(settings.Paths, now, now.Add(time.Duration(settings.LookaheadHours)*time.Hour))
+
+	calendarMu.Lock()
+	calendarBlocks = blocks
+	calendarMu.Unlock()
+}
+
+// upcomingMeetings returns the cached busy blocks that haven't ended yet
+func upcomingMeetings(now time.Time) []BusyBlock {
+	calendarMu.Lock()
+	defer calendarMu.Unlock()
+
+	upcoming := make([]BusyBlock, 0)
+	for _, block := range calendarBlocks {
+		if block.End.After(now) {
+			upcoming = append(upcoming, block)
+		}
+	}
+	return upcoming
+}
+
+// loadBusyBlocks reads .ics files and directories, sorted by start time
+func loadBusyBlocks(paths []string, from, to time.Time) []BusyBlock {
+	var blocks []BusyBlock
+	for _, path := range paths {
+		path = expandHome(path)
+		files := []string{path}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			files, _ = filepath.Glob(filepath.Join(path, "*.ics"))
+		}
+		for _, file := range files {
+			found, err := readICSFile(file, from, to)
+			if err != nil {
+				log.Printf("calendar: %s: %v", file, err)
+				continue
+			}
+			blocks = append(blocks, found...)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start.Before(blocks[j].Start) })
+	return blocks
+}
+
+// readICSFile parses one calendar file
+func readICSFile(path string, from, to time.Time) ([]BusyBlock, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseICS(file, from, to)
+}
+
+// icsProperty is one unfolded content line, e.g. DTSTART;TZID=Europe/London:20240301T090000
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsEvent collects the VEVENT properties we care about
+type icsEvent struct {
+	start, end  time.Time
+	allDay      bool
+	duration    time.Duration
+	summary     string
+	transparent bool
+	cancelled   bool
+	rrule       string
+	exdates     []time.Time
+	hasEnd      bool
+	uid         string
+	moved       time.Time // RECURRENCE-ID: the occurrence of the UID's series this replaces
+}
+
+// parseICS returns the busy time between from and to. All-day, free
+// (TRANSP:TRANSPARENT) and cancelled events don't count as busy. A moved or
+// cancelled occurrence (RECURRENCE-ID) replaces the one in its series.
+func parseICS(r io.Reader, from, to time.Time) ([]BusyBlock, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*icsEvent
+	var event *icsEvent
+	nested := 0 // Depth inside VALARM and friends, whose properties aren't the event's
+	for _, line := range lines {
+		prop, ok := parseICSLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop.Name == "BEGIN" && prop.Value == "VEVENT":
+			event = &icsEvent{}
+			nested = 0
+		case prop.Name == "END" && prop.Value == "VEVENT":
+			if event != nil {
+				events = append(events, event)
+			}
+			event = nil
+		case event != nil && prop.Name == "BEGIN":
+			nested++
+		case event != nil && prop.Name == "END":
+			nested--
+		case event != nil && nested == 0:
+			event.set(prop)
+		}
+	}
+
+	// The series leaves out occurrences that were moved (or cancelled) on their own
+	moved := map[string][]time.Time{}
+	for _, event := range events {
+		if event.uid != "" && !event.moved.IsZero() {
+			moved[event.uid] = append(moved[event.uid], event.moved)
+		}
+	}
+	var blocks []BusyBlock
+	for _, event := range events {
+		if event.moved.IsZero() {
+			event.exdates = append(event.exdates, moved[event.uid]...)
+		}
+		blocks = append(blocks, event.occurrences(from, to)...)
+	}
+	return blocks, nil
+}
+
+// set records one property of the event
+func (e *icsEvent) set(prop icsProperty) {
+	switch prop.Name {
+	case "DTSTART":
+		e.start, e.allDay = parseICSTime(prop)
+	case "DTEND":
+		e.end, _ = parseICSTime(prop)
+		e.hasEnd = true
+	case "DURATION":
+		e.duration = parseICSDuration(prop.Value)
+	case "SUMMARY":
+		e.summary = unescapeICSText(prop.Value)
+	case "TRANSP":
+		e.transparent = prop.Value == "TRANSPARENT"
+	case "STATUS":
+		e.cancelled = prop.Value == "CANCELLED"
+	case "RRULE":
+		e.rrule = prop.Value
+	case "UID":
+		e.uid = prop.Value
+	case "RECURRENCE-ID":
+		e.moved, _ = parseICSTime(prop)
+	case "EXDATE":
+		for _, value := range strings.Split(prop.Value, ",") {
+			exdate, _ := parseICSTime(icsProperty{Params: prop.Params, Value: value})
+			e.exdates = append(e.exdates, exdate)
+		}
+	}
+}
+
+// occurrences expands the event into busy blocks overlapping [from, to)
+func (e *icsEvent) occurrences(from, to time.Time) []BusyBlock {
+	if e.start.IsZero() || e.allDay || e.transparent || e.cancelled {
+		return nil
+	}
+	length := e.end.Sub(e.start)
+	if !e.hasEnd {
+		length = e.duration
+	}
+	if length <= 0 {
+		return nil
+	}
+
+	starts := []time.Time{e.start}
+	if e.rrule != "" {
+		starts = expandRRule(e.start, e.rrule, from.Add(-length), to)
+	}
+
+	var blocks []BusyBlock
+	for _, start := range starts {
+		end := start.Add(length)
+		if !start.Before(to) || !end.After(from) || e.excluded(start) {
+			continue
+		}
+		blocks = append(blocks, BusyBlock{Start: start, End: end, Summary: e.summary})
+	}
+	return blocks
+}
+
+// excluded reports whether an occurrence was removed with EXDATE
+func (e *icsEvent) excluded(start time.Time) bool {
+	for _, exdate := range e.exdates {
+		if exdate.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// unfoldICS joins continuation lines (RFC 5545 3.1)
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICSLine splits NAME;PARAM=VALUE:VALUE, minding quoted parameter values
+func parseICSLine(line string) (icsProperty, bool) {
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icsProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{
+		Name:   strings.ToUpper(parts[0]),
+		Params: map[string]string{},
+		Value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.Params[strings.ToUpper(key)] = strings.Trim(value, "\"")
+		}
+	}
+	return prop, true
+}
+
+// parseICSTime handles UTC, TZID and floating date-times, and all-day dates
+func parseICSTime(prop icsProperty) (t time.Time, allDay bool) {
+	value := prop.Value
+	if prop.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, _ = time.ParseInLocation("20060102", value, time.Local)
+		return t, true
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, _ = time.Parse("20060102T150405Z", value)
+		return t, false
+	}
+	location := time.Local
+	if tzid := prop.Params["TZID"]; tzid != "" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			location = loc
+		}
+	}
+	t, _ = time.ParseInLocation("20060102T150405", value, location)
+	return t, false
+}
+
+// parseICSDuration understands P1W, P1D, PT1H30M and friends
+func parseICSDuration(value string) time.Duration {
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	value = strings.TrimPrefix(value, "P")
+
+	var total time.Duration
+	number := ""
+	for _, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T':
+			// Time part follows; units below tell them apart
+		default:
+			n, _ := strconv.Atoi(number)
+			number = ""
+			switch c {
+			case 'W':
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case 'D':
+				total += time.Duration(n) * 24 * time.Hour
+			case 'H':
+				total += time.Duration(n) * time.Hour
+			case 'M':
+				total += time.Duration(n) * time.Minute
+			case 'S':
+				total += time.Duration(n) * time.Second
+			}
+		}
+	}
+	if negative {
+		return -total
+	}
+	return total
+}
+
+// unescapeICSText undoes TEXT escaping
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// icsWeekdays maps BYDAY codes to weekdays
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// maxRecurrences stops runaway rules
+const maxRecurrences = 5000
+
+// expandRRule lists occurrence starts in [from, to) for DAILY and WEEKLY
+// rules with INTERVAL, COUNT, UNTIL and (weekly) BYDAY. Other rules only keep
+// the first occurrence. Without COUNT it skips straight to the window, so
+// long-running series keep going; with it, every occurrence since DTSTART
+// counts.
+func expandRRule(start time.Time, rrule string, from, to time.Time) []time.Time {
+	rule := map[string]string{}
+	for _, part := range strings.Split(rrule, ";") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			rule[strings.ToUpper(key)] = value
+		}
+	}
+
+	interval, _ := strconv.Atoi(rule["INTERVAL"])
+	if interval < 1 {
+		interval = 1
+	}
+	count, _ := strconv.Atoi(rule["COUNT"])
+	until := to
+	if rule["UNTIL"] != "" {
+		if t, _ := parseICSTime(icsProperty{Value: rule["UNTIL"]}); !t.IsZero() && t.Before(until) {
+			until = t.Add(time.Second) // UNTIL is inclusive
+		}
+	}
+
+	var days []time.Weekday
+	for _, code := range strings.Split(rule["BYDAY"], ",") {
+		if day, ok := icsWeekdays[strings.ToUpper(code)]; ok {
+			days = append(days, day)
+		}
+	}
+
+	var starts []time.Time
+	seen := 0 // Occurrences so far, for COUNT
+	add := func(t time.Time) bool {
+		if !t.Before(until) || (count > 0 && seen >= count) || len(starts) >= maxRecurrences {
+			return false
+		}
+		seen++
+		if !t.Before(from) {
+			starts = append(starts, t)
+		}
+		return true
+	}
+	// skipTo is how many whole intervals of period fit between first and from,
+	// less one to stay clear of daylight saving changes
+	skipTo := func(first time.Time, period time.Duration) int {
+		if count > 0 || !from.After(first) {
+			return 0
+		}
+		return max(0, int(from.Sub(first)/period)/interval-1) * interval
+	}
+
+	switch rule["FREQ"] {
+	case "DAILY":
+		for t := start.AddDate(0, 0, skipTo(start, 24*time.Hour)); add(t); t = t.AddDate(0, 0, interval) {
+		}
+	case "WEEKLY":
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		weekStart := start.AddDate(0, 0, -int(start.Weekday())) // Sunday of the first week
+		for week := skipTo(weekStart, 7*24*time.Hour); ; week += interval {
+			for day := time.Sunday; day <= time.Saturday; day++ {
+				t := weekStart.AddDate(0, 0, week*7+int(day))
+				if !containsWeekday(days, day) || t.Before(start) {
+					continue
+				}
+				if !add(t) {
+					return starts
+				}
+			}
+		}
+	default:
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// containsWeekday reports whether day is in days
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// meetingOverlapping returns the first busy block inside [now, now+length)
+func meetingOverlapping(blocks []BusyBlock, now time.Time, length time.Duration) (BusyBlock, bool) {
+	end := now.Add(length)
+	for _, block := range blocks {
+		if block.Start.Before(end) && block.End.After(now) {
+			return block, true
+		}
+	}
+	return BusyBlock{}, false
+}
+
+// insertSession puts a slot into the cycle at index
+func (sm *SessionManager) insertSession(index int, slot SessionSlot) {
+	sm.Sessions = append(sm.Sessions, SessionSlot{})
+	copy(sm.Sessions[index+1:], sm.Sessions[index:])
+	sm.Sessions[index] = slot
+}
+
+// meetingBreak is a long break standing in for a meeting
+func meetingBreak(block BusyBlock, length time.Duration) SessionSlot {
+	return SessionSlot{
+		Type:     SessionLongBreak,
+		Duration: int(length.Seconds()),
+		Meeting:  block.Summary,
+	}
+}
+
+// ShortenForMeeting ends the current work session when the meeting starts
+// and adds a long break for the meeting straight after it
+func (sm *SessionManager) ShortenForMeeting(block BusyBlock, now time.Time) {
+	current := sm.GetCurrentSession()
+	if current == nil {
+		return
+	}
+	current.Duration = int(block.Start.Sub(now).Seconds())
+	sm.insertSession(sm.CurrentIndex+1, meetingBreak(block, block.End.Sub(block.Start)))
+}
+
+// shortenedForMeeting reports whether the current session was already cut
+// short for this meeting (its break comes straight after)
+func (sm *SessionManager) shortenedForMeeting(block BusyBlock) bool {
+	next := sm.CurrentIndex + 1
+	return next < len(sm.Sessions) && sm.Sessions[next].Type == SessionLongBreak &&
+		sm.Sessions[next].Meeting == block.Summary &&
+		sm.Sessions[next].Duration == int(block.End.Sub(block.Start).Seconds())
+}
+
+// SkipForMeeting makes a long break lasting until the meeting ends the
+// current session; the work session waits until afterwards
+func (sm *SessionManager) SkipForMeeting(block BusyBlock, now time.Time) {
+	if sm.GetCurrentSession() == nil {
+		return
+	}
+	sm.Sessions[sm.CurrentIndex].Current = false
+	slot := meetingBreak(block, block.End.Sub(now))
+	slot.Current = true
+	sm.insertSession(sm.CurrentIndex, slot)
+}
+
+// planAroundMeetings applies the meeting policy before a fresh work session starts
+func planAroundMeetings() {
+	current := sessionManager.GetCurrentSession()
+	if current == nil || current.Type != SessionWork {
+		return
+	}
+	now := time.Now()
+	block, ok := meetingOverlapping(upcomingMeetings(now), now, time.Duration(current.Duration)*time.Second)
+	if !ok {
+		return
+	}
+	if sessionManager.shortenedForMeeting(block) {
+		// Planned on an earlier start (then reset): just end at the meeting again
+		if left := int(block.Start.Sub(now).Seconds()); left > 0 && left < current.Duration {
+			current.Duration = left
+			timeRemaining = left
+		}
+		return
+	}
+
+	settings := DefaultSettings.Calendar
+	when := block.Start.Format("15:04")
+	policy := settings.OnOverlap
+	minimum := time.Duration(settings.MinWorkMinutes) * time.Minute
+	if minimum < time.Minute {
+		minimum = time.Minute
+	}
+	if policy == MeetingShorten && block.Start.Sub(now) < minimum {
+		policy = MeetingSkip // Not worth a stub of a session (or it's already started)
+	}
+
+	work := *current // The plan below may move the slots around
+	var message string
+	switch policy {
+	case MeetingShorten:
+		sessionManager.ShortenForMeeting(block, now)
+		message = fmt.Sprintf("Session cut short to end at %s for %s", when, block.Summary)
+	case MeetingSkip:
+		sessionManager.SkipForMeeting(block, now)
+		message = fmt.Sprintf("%s at %s - taking a break until it's over", block.Summary, when)
+	default:
+		message = fmt.Sprintf("%s at %s cuts into this session", block.Summary, when)
+	}
+	timeRemaining = sessionManager.GetCurrentSession().Duration
+
+	alert := Alert{
+		Event:   EventMeetingAhead,
+		Session: work,
+		Title:   "📅 Meeting ahead",
+		Message: message,
+	}
+	dispatchAlert(alert, alertRoutes(), builtinNotifiers)
+}
+
+// formatMeeting is how a busy block shows in the session list
+func formatMeeting(block BusyBlock) string {
+	return fmt.Sprintf("📅 %s %s", block.Start.Format("15:04"), block.Summary)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"DTSTART;TZID=Europe/London:20240304T093000\r\n" +
+	"DTEND;TZID=Europe/London:20240304T094500\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=6\r\n" +
+	"EXDATE;TZID=Europe/London:20240306T093000\r\n" +
+	"SUMMARY:Daily\\, stand-up\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT10M\r\n" +
+	"DURATION:PT5M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review\r\n" +
+	"DTSTART:20240305T140000Z\r\n" +
+	"DURATION:PT1H\r\n" +
+	"SUMMARY:Design review with a very long title that gets fol\r\n" +
+	" ded onto a second line\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240305\r\n" +
+	"DTEND;VALUE=DATE:20240306\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20240305T160000Z\r\n" +
+	"DTEND:20240305T170000Z\r\n" +
+	"TRANSP:TRANSPARENT\r\n" +
+	"SUMMARY:Free time\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20240305T170000Z\r\n" +
+	"DTEND:20240305T180000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"SUMMARY:Cancelled\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("No timezone database")
+	}
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+
+	blocks, err := parseICS(strings.NewReader(testICS), from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var standups, reviews []BusyBlock
+	for _, block := range blocks {
+		switch {
+		case block.Summary == "Daily, stand-up":
+			standups = append(standups, block)
+		case strings.HasPrefix(block.Summary, "Design review"):
+			reviews = append(reviews, block)
+		default:
+			t.Errorf("Unexpected busy block %q", block.Summary)
+		}
+	}
+
+	// Mon 4, (Wed 6 excluded), Fri 8 fall inside the window
+	if len(standups) != 2 {
+		t.Fatalf("Expected 2 stand-ups, got %d: %v", len(standups), standups)
+	}
+	if !standups[1].Start.Equal(time.Date(2024, 3, 8, 9, 30, 0, 0, london)) {
+		t.Errorf("Unexpected second stand-up %v", standups[1].Start)
+	}
+	if standups[0].End.Sub(standups[0].Start) != 15*time.Minute {
+		t.Errorf("VALARM duration leaked into the event: %v", standups[0].End.Sub(standups[0].Start))
+	}
+
+	if len(reviews) != 1 || reviews[0].Summary != "Design review with a very long title that gets folded onto a second line" {
+		t.Fatalf("Folded summary not joined: %v", reviews)
+	}
+	if !reviews[0].End.Equal(time.Date(2024, 3, 5, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("DURATION not applied: %v", reviews[0].End)
+	}
+}
+
+func TestExpandRRuleDaily(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	starts := expandRRule(start, "FREQ=DAILY;INTERVAL=2;UNTIL=20240307T090000Z", start, start.AddDate(0, 1, 0))
+	if len(starts) != 4 || !starts[3].Equal(start.AddDate(0, 0, 6)) {
+		t.Errorf("Expected every other day up to and including the 7th, got %v", starts)
+	}
+}
+
+func TestExpandRRuleLongRunning(t *testing.T) {
+	start := time.Date(2000, 1, 3, 9, 0, 0, 0, time.UTC) // A Monday
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	if starts := expandRRule(start, "FREQ=DAILY", from, to); len(starts) != 7 || !starts[0].Equal(from.Add(9*time.Hour)) {
+		t.Errorf("A daily series from 2000 should still be going, got %v", starts)
+	}
+	if starts := expandRRule(start, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", from, to.AddDate(0, 0, 7)); len(starts) != 2 {
+		t.Errorf("Expected Monday and Wednesday of one fortnight, got %v", starts)
+	} else if weeks := int(starts[0].Sub(start).Hours()/24/7 + 0.5); weeks%2 != 0 {
+		t.Errorf("Skipping ahead lost the fortnightly rhythm, got %v", starts)
+	}
+	if starts := expandRRule(start, "FREQ=DAILY;COUNT=3", from, to); len(starts) != 0 {
+		t.Errorf("COUNT counts from the start, got %v", starts)
+	}
+}
+
+func TestParseICSMovedOccurrences(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT", "UID:standup", "DTSTART:20200106T090000Z", "DTEND:20200106T091500Z",
+		"RRULE:FREQ=DAILY", "SUMMARY:Standup", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:standup", "RECURRENCE-ID:20261020T090000Z",
+		"DTSTART:20261020T110000Z", "DTEND:20261020T111500Z", "SUMMARY:Standup (late)", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:standup", "RECURRENCE-ID:20261021T090000Z", "STATUS:CANCELLED",
+		"DTSTART:20261021T090000Z", "DTEND:20261021T091500Z", "SUMMARY:Standup", "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	// Starting mid-standup still catches it
+	from := time.Date(2026, 10, 19, 9, 5, 0, 0, time.UTC)
+	blocks, err := parseICS(strings.NewReader(ics), from, from.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, block := range blocks {
+		got = append(got, block.Start.Format("Jan 2 15:04"))
+	}
+	sort.Strings(got)
+	if want := "Oct 19 09:00, Oct 20 11:00, Oct 22 09:00"; strings.Join(got, ", ") != want {
+		t.Errorf("Expected %s, got %v", want, got)
+	}
+}
+
+func TestLoadBusyBlocksFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "work.ics"), []byte(testICS), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a calendar"), 0644)
+
+	from := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	blocks := loadBusyBlocks([]string{dir}, from, from.Add(24*time.Hour))
+	if len(blocks) != 1 || !strings.HasPrefix(blocks[0].Summary, "Design review") {
+		t.Errorf("Expected just the design review, got %v", blocks)
+	}
+}
+
+func TestMeetingOverlapping(t *testing.T) {
+	now := time.Date(2024, 3, 5, 13, 30, 0, 0, time.UTC)
+	blocks := []BusyBlock{
+		{Start: now.Add(-time.Hour), End: now, Summary: "over"},
+		{Start: now.Add(20 * time.Minute), End: now.Add(50 * time.Minute), Summary: "review"},
+	}
+	block, ok := meetingOverlapping(blocks, now, 25*time.Minute)
+	if !ok || block.Summary != "review" {
+		t.Errorf("Expected the review to overlap, got %v %v", block, ok)
+	}
+	if _, ok := meetingOverlapping(blocks, now, 20*time.Minute); ok {
+		t.Error("A session ending as the meeting starts doesn't overlap")
+	}
+}
+
+func TestShortenForMeeting(t *testing.T) {
+	sm := newTestSessionManager(t, 2) // work, break, work
+	now := time.Date(2024, 3, 5, 13, 30, 0, 0, time.UTC)
+	meeting := BusyBlock{Start: now.Add(15 * time.Minute), End: now.Add(45 * time.Minute), Summary: "Review"}
+
+	sm.ShortenForMeeting(meeting, now)
+
+	if sm.Sessions[0].Duration != 15*60 {
+		t.Errorf("Work should end when the meeting starts, got %d", sm.Sessions[0].Duration)
+	}
+	inserted := sm.Sessions[1]
+	if inserted.Type != SessionLongBreak || inserted.Duration != 30*60 || inserted.GetSessionLabel() != "📅 Review" {
+		t.Errorf("Expected a meeting break next, got %+v", inserted)
+	}
+	if len(sm.Sessions) != 4 || !sm.Sessions[0].Current || inserted.Current {
+		t.Errorf("Unexpected cycle %+v", sm.Sessions)
+	}
+}
+
+func TestSkipForMeeting(t *testing.T) {
+	sm := newTestSessionManager(t, 2)
+	now := time.Date(2024, 3, 5, 13, 30, 0, 0, time.UTC)
+	meeting := BusyBlock{Start: now.Add(5 * time.Minute), End: now.Add(time.Hour), Summary: "All hands"}
+
+	sm.SkipForMeeting(meeting, now)
+
+	current := sm.GetCurrentSession()
+	if current.Type != SessionLongBreak || current.Duration != 60*60 || current.Meeting != "All hands" {
+		t.Errorf("Expected a break until the meeting ends, got %+v", current)
+	}
+	if sm.Sessions[1].Type != SessionWork || sm.Sessions[1].Current {
+		t.Errorf("Work session should wait until after, got %+v", sm.Sessions[1])
+	}
+}
+
+func TestRestartDoesNotPlanMeetingTwice(t *testing.T) {
+	useTestConfigDir(t)
+	sessionManager = newTestSessionManager(t, 2) // work, break, work
+	DefaultSettings.Calendar = CalendarSettings{OnOverlap: MeetingShorten, MinWorkMinutes: 10}
+	DefaultSettings.AlertRoutes = []AlertRoute{{Event: EventSessionFinished, Notifiers: []string{"desktop"}}}
+	useTestUI(t)
+	currentState = TimerReady
+	timeRemaining = sessionManager.GetCurrentSession().Duration
+
+	// The meeting moves two minutes closer before every start, as if the
+	// session ran a while before each reset
+	now := time.Now()
+	meetingIn := func(minutes time.Duration) {
+		calendarMu.Lock()
+		calendarBlocks = []BusyBlock{{Start: now.Add(minutes * time.Minute), End: now.Add((minutes + 30) * time.Minute), Summary: "Review"}}
+		calendarMu.Unlock()
+	}
+	t.Cleanup(func() {
+		calendarMu.Lock()
+		calendarBlocks = nil
+		calendarMu.Unlock()
+		resetTimer()
+	})
+
+	for _, minutes := range []time.Duration{15, 13} {
+		meetingIn(minutes)
+		startTimer()
+		resetTimer()
+	}
+	meetingIn(11)
+	startTimer()
+
+	if len(sessionManager.Sessions) != 4 {
+		t.Fatalf("Expected one meeting break in the cycle, got %+v", sessionManager.Sessions)
+	}
+	if timeRemaining > 11*60 || timeRemaining < 10*60 {
+		t.Errorf("Work should still end when the meeting starts, got %d seconds", timeRemaining)
+	}
+}
+
+func TestMeetingAlertsAreRouted(t *testing.T) {
+	sessionManager = newTestSessionManager(t, 2)
+	DefaultSettings.Calendar = CalendarSettings{OnOverlap: MeetingWarn}
+	calendarMu.Lock()
+	calendarBlocks = []BusyBlock{{Start: time.Now().Add(10 * time.Minute), End: time.Now().Add(time.Hour), Summary: "Standup"}}
+	calendarMu.Unlock()
+	recorder := &recordingNotifier{name: "recorder"}
+	builtinNotifiers["recorder"] = recorder
+	t.Cleanup(func() {
+		delete(builtinNotifiers, "recorder")
+		calendarMu.Lock()
+		calendarBlocks = nil
+		calendarMu.Unlock()
+	})
+
+	// Routes without meeting_ahead keep meetings quiet
+	DefaultSettings.AlertRoutes = []AlertRoute{{Event: EventSessionFinished, Notifiers: []string{"recorder"}}}
+	planAroundMeetings()
+	if len(recorder.alerts) != 0 {
+		t.Fatalf("Meeting alert ignored the routes: %+v", recorder.alerts)
+	}
+
+	DefaultSettings.AlertRoutes = []AlertRoute{{Event: EventMeetingAhead, SessionType: SessionWork, Notifiers: []string{"recorder"}}}
+	planAroundMeetings()
+	if len(recorder.alerts) != 1 || !strings.Contains(recorder.alerts[0].Message, "Standup") {
+		t.Errorf("Expected one routed meeting alert, got %+v", recorder.alerts)
+	}
+}
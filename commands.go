@@ -0,0 +1,19 @@
+package main
+
+// runSubcommand handles command-line modes that don't open the GUI.
+// Returns false if args don't name a known subcommand.
+func runSubcommand(args []string) (int, bool) {
+	switch args[0] {
+	case "status":
+		return runStatusCommand(args[1:]), true
+	case "export":
+		return runExportCommand(args[1:]), true
+	case "import":
+		return runImportCommand(args[1:]), true
+	case "compact":
+		return runCompactCommand(args[1:]), true
+	case "serve-sync":
+		return runServeSyncCommand(args[1:]), true
+	}
+	return 0, false
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// exportFormatters write history records in each export format
+var exportFormatters = map[string]func(io.Writer, []HistoryRecord) error{
+	"ics":      writeHistoryICS,
+	"csv":      writeHistoryCSV,
+	"json":     writeHistoryJSONLines,
+	"markdown": writeHistoryMarkdown,
+}
+
+// exportFormatNames lists the formats for help and error messages
+func exportFormatNames() string {
+	names := make([]string, 0, len(exportFormatters))
+	for name := range exportFormatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// runExportCommand writes the history log in another format
+func runExportCommand(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "ics", "output format: "+exportFormatNames())
+	output := flags.String("output", "", "file to write (default stdout)")
+	fromDay := flags.String("from", "", "first day to include (YYYY-MM-DD)")
+	toDay := flags.String("to", "", "last day to include (YYYY-MM-DD)")
+	days := flags.Int("days", 0, "only the last N days, including today")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	from, err := parseReportDay(*fromDay)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro export: bad --from:", err)
+		return 2
+	}
+	to, err := parseReportDay(*toDay)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro export: bad --to:", err)
+		return 2
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1) // --to is inclusive
+	}
+	if *days > 0 {
+		from = lastDaysStart(*days)
+	}
+
+	formatter, ok := exportFormatters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q (available: %s)\n", *format, exportFormatNames())
+		return 2
+	}
+
+	records, err := openHistoryStore().Query(HistoryQuery{From: from, To: to})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro export:", err)
+		return 1
+	}
+
+	var buf bytes.Buffer
+	if err := formatter(&buf, records); err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro export:", err)
+		return 1
+	}
+	if *output == "" {
+		os.Stdout.Write(buf.Bytes())
+		return 0
+	}
+	if err := writeFileAtomic(*output, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro export:", err)
+		return 1
+	}
+	return 0
+}
+
+// setupCalendarExport keeps CalendarSettings.ExportICS up to date as sessions finish
+func setupCalendarExport() {
+	if DefaultSettings.Calendar.ExportICS == "" || historyStore == nil {
+		return
+	}
+	go updateCalendarExport()
+	onLifecycleEvent(func(sessionEvent SessionEvent) {
+		if sessionEvent.Event == LifecycleSessionFinish {
+			go updateCalendarExport()
+		}
+	})
+}
+
+// updateCalendarExport rewrites the continuously exported .ics file
+func updateCalendarExport() {
+	path := expandHome(DefaultSettings.Calendar.ExportICS)
+	records, err := historyStore.Query(HistoryQuery{SessionType: SessionWork, Kinds: []HistoryKind{HistoryCompleted}})
+	if err != nil {
+		log.Printf("calendar export: %v", err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := writeHistoryICS(&buf, records); err != nil {
+		log.Printf("calendar export: %v", err)
+		return
+	}
+	if err := writeFileAtomic(path, buf.Bytes(), 0644); err != nil {
+		log.Printf("calendar export: %v", err)
+	}
+}
+
+// writeHistoryICS writes completed work sessions as VEVENTs. UIDs come from
+// the record IDs, so calendar apps update events on re-import.
+func writeHistoryICS(w io.Writer, records []HistoryRecord) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//GoModoro//Focus sessions//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:GoModoro focus",
+	}
+	for _, record := range records {
+		if record.Kind != HistoryCompleted || record.SessionType != SessionWork || record.ID == "" {
+			continue
+		}
+		summary := record.Task
+		if summary == "" {
+			summary = fmt.Sprintf("Focus session %d", record.SessionNum)
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+record.ID+"@gomodoro",
+			"DTSTAMP:"+formatICSTime(record.End), // Stable, so re-exports are identical
+			"DTSTART:"+formatICSTime(record.Start),
+			"DTEND:"+formatICSTime(record.End),
+			"SUMMARY:🍅 "+escapeICSText(summary),
+			"CATEGORIES:Pomodoro,"+escapeICSText(string(record.SessionType)),
+			"TRANSP:OPAQUE",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICSLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatICSTime writes a UTC date-time
+func formatICSTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeICSText escapes TEXT values
+func escapeICSText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(value)
+}
+
+// foldICSLine splits lines longer than 75 octets without breaking UTF-8 characters
+func foldICSLine(line string) string {
+	var folded strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			folded.WriteString("\r\n ")
+			width = 1
+		}
+		folded.WriteRune(r)
+		width += size
+	}
+	return folded.String()
+}
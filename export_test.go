@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testHistory is a morning's worth of records
+func testHistory() []HistoryRecord {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	return []HistoryRecord{
+		{ID: "a1", Kind: HistoryCompleted, SessionType: SessionWork, SessionNum: 1, Task: "Write docs; then, ship",
+			Start: start, End: start.Add(25 * time.Minute), Duration: 1500, Elapsed: 1500},
+		{ID: "b2", Kind: HistoryCompleted, SessionType: SessionShortBreak,
+			Start: start.Add(25 * time.Minute), End: start.Add(30 * time.Minute), Duration: 300, Elapsed: 300},
+		{ID: "c3", Kind: HistorySkipped, SessionType: SessionWork, SessionNum: 2, Task: "Write docs; then, ship",
+			Start: start.Add(30 * time.Minute), End: start.Add(40 * time.Minute), Duration: 1500, Elapsed: 600},
+		{ID: "d4", Kind: HistoryLocked, SessionType: SessionWork, Start: start.Add(45 * time.Minute), End: start.Add(50 * time.Minute)},
+		{ID: "e5", Kind: HistoryCompleted, SessionType: SessionWork, SessionNum: 3,
+			Start: start.Add(60 * time.Minute), End: start.Add(85 * time.Minute), Duration: 1500, Elapsed: 1500},
+	}
+}
+
+func TestWriteHistoryICS(t *testing.T) {
+	var first, second bytes.Buffer
+	if err := writeHistoryICS(&first, testHistory()); err != nil {
+		t.Fatal(err)
+	}
+	writeHistoryICS(&second, testHistory())
+	if first.String() != second.String() {
+		t.Error("Re-exporting the same history should give identical output")
+	}
+
+	ics := first.String()
+	if strings.Count(ics, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Only completed work sessions should be exported:\n%s", ics)
+	}
+	for _, want := range []string{"UID:a1@gomodoro", "UID:e5@gomodoro", "CATEGORIES:Pomodoro,work",
+		`SUMMARY:🍅 Write docs\; then\, ship`, "SUMMARY:🍅 Focus session 3", "DTSTART:20240304T090000Z"} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("Missing %q in:\n%s", want, ics)
+		}
+	}
+
+	// Our own calendar reader should see the same blocks
+	blocks, err := parseICS(strings.NewReader(ics), time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(blocks) != 2 || blocks[0].Summary != "🍅 Write docs; then, ship" {
+		t.Errorf("Export didn't round-trip: %v %v", blocks, err)
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("🍅", 30)
+	folded := foldICSLine(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("Folded line too long (%d octets)", len(part))
+		}
+	}
+	unfolded, _ := unfoldICS(strings.NewReader(folded))
+	if len(unfolded) != 1 || unfolded[0] != line {
+		t.Errorf("Folding should be reversible, got %q", unfolded)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/godbus/dbus/v5"
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// GlobalShortcuts portal (Wayland, and X11 desktops that have it)
+const (
+	portalService            = "org.freedesktop.portal.Desktop"
+	portalPath               = "/org/freedesktop/portal/desktop"
+	globalShortcutsInterface = "org.freedesktop.portal.GlobalShortcuts"
+	portalRequestInterface   = "org.freedesktop.portal.Request"
+)
+
+// portalTimeout is how long to wait for the portal (binding may ask the user)
+var portalTimeout = 2 * time.Minute
+
+// Running global hotkeys, released on exit
+var globalHotkeys interface{ Close() error }
+
+// setupGlobalHotkeys grabs the configured system-wide shortcuts, through the
+// portal on Wayland and straight from the X server otherwise
+func setupGlobalHotkeys() {
+	bindings := parseHotkeys(DefaultSettings.Hotkeys.Global, "global")
+	delete(bindings, "settings") // Needs the window anyway
+	if len(bindings) == 0 {
+		return
+	}
+
+	// Binding through the portal can wait on the user, so don't hold up startup
+	go func() {
+		var err error
+		if os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("DISPLAY") == "" {
+			var portal *portalHotkeys
+			if portal, err = bindPortalHotkeys(nil, bindings, runHotkey); err == nil {
+				globalHotkeys = portal
+			}
+		} else {
+			var x11 *x11Hotkeys
+			if x11, err = grabX11Hotkeys(bindings, runHotkey); err == nil {
+				globalHotkeys = x11
+			}
+		}
+		if err != nil {
+			log.Printf("hotkeys: global shortcuts unavailable: %v", err)
+		}
+	}()
+}
+
+// portalHotkeys is a GlobalShortcuts portal session
+type portalHotkeys struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+}
+
+// portalRequests numbers request tokens
+var portalRequests atomic.Int64
+
+// bindPortalHotkeys asks the portal for the shortcuts (the desktop may let the
+// user confirm or change them) and calls press when one is used. A nil conn
+// means the session bus.
+func bindPortalHotkeys(conn *dbus.Conn, bindings map[string]keyBinding, press func(string)) (*portalHotkeys, error) {
+	if conn == nil {
+		var err error
+		if conn, err = dbus.ConnectSessionBus(); err != nil {
+			return nil, err
+		}
+	}
+	portal := &portalHotkeys{conn: conn}
+
+	results, err := portal.request("CreateSession", map[string]dbus.Variant{
+		"session_handle_token": dbus.MakeVariant(fmt.Sprintf("gomodoro_%d", os.Getpid())),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating portal session: %w", err)
+	}
+	switch handle := results["session_handle"].Value().(type) {
+	case string:
+		portal.session = dbus.ObjectPath(handle)
+	case dbus.ObjectPath:
+		portal.session = handle
+	default:
+		return nil, errors.New("portal gave no session")
+	}
+
+	// Listen before binding so no press is missed
+	if err := conn.AddMatchSignal(dbus.WithMatchObjectPath(portalPath),
+		dbus.WithMatchInterface(globalShortcutsInterface), dbus.WithMatchMember("Activated")); err != nil {
+		return nil, err
+	}
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	go func() {
+		for signal := range signals { // Closed with the connection
+			if signal.Name != globalShortcutsInterface+".Activated" || len(signal.Body) < 2 {
+				continue
+			}
+			if session, _ := signal.Body[0].(dbus.ObjectPath); session != portal.session {
+				continue
+			}
+			if id, ok := signal.Body[1].(string); ok && bindings[id] != (keyBinding{}) {
+				press(id)
+			}
+		}
+	}()
+
+	type shortcut struct {
+		ID      string
+		Options map[string]dbus.Variant
+	}
+	var shortcuts []shortcut
+	for action, binding := range bindings {
+		shortcuts = append(shortcuts, shortcut{ID: action, Options: map[string]dbus.Variant{
+			"description":       dbus.MakeVariant(hotkeyActions[action]),
+			"preferred_trigger": dbus.MakeVariant(portalTrigger(binding)),
+		}})
+	}
+	if _, err := portal.request("BindShortcuts", map[string]dbus.Variant{}, portal.session, shortcuts, ""); err != nil {
+		portal.Close()
+		return nil, fmt.Errorf("binding shortcuts: %w", err)
+	}
+	return portal, nil
+}
+
+// request calls a portal method and waits for its Response signal. The
+// options go last, as in every portal method.
+func (p *portalHotkeys) request(method string, options map[string]dbus.Variant, args ...interface{}) (map[string]dbus.Variant, error) {
+	token := fmt.Sprintf("gomodoro%d", portalRequests.Add(1))
+	options["handle_token"] = dbus.MakeVariant(token)
+	sender := strings.ReplaceAll(strings.TrimPrefix(p.conn.Names()[0], ":"), ".", "_")
+	expected := dbus.ObjectPath(portalPath + "/request/" + sender + "/" + token)
+
+	if err := p.conn.AddMatchSignal(dbus.WithMatchInterface(portalRequestInterface), dbus.WithMatchMember("Response")); err != nil {
+		return nil, err
+	}
+	signals := make(chan *dbus.Signal, 10)
+	p.conn.Signal(signals)
+	defer p.conn.RemoveSignal(signals)
+
+	var handle dbus.ObjectPath
+	call := p.conn.Object(portalService, portalPath).Call(globalShortcutsInterface+"."+method, 0, append(args, options)...)
+	if err := call.Store(&handle); err != nil {
+		return nil, err
+	}
+
+	timeout := time.After(portalTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Name != portalRequestInterface+".Response" || (signal.Path != handle && signal.Path != expected) || len(signal.Body) < 2 {
+				continue
+			}
+			if response, _ := signal.Body[0].(uint32); response != 0 {
+				return nil, fmt.Errorf("%s refused (response %d)", method, response)
+			}
+			results, _ := signal.Body[1].(map[string]dbus.Variant)
+			return results, nil
+		case <-timeout:
+			return nil, fmt.Errorf("%s: no answer from the portal", method)
+		}
+	}
+}
+
+// Close ends the portal session, releasing the shortcuts
+func (p *portalHotkeys) Close() error {
+	if p.session != "" {
+		p.conn.Object(portalService, p.session).Call("org.freedesktop.portal.Session.Close", 0)
+	}
+	return p.conn.Close()
+}
+
+// portalTrigger spells a binding the way the shortcuts spec does, e.g. "CTRL+ALT+p"
+func portalTrigger(binding keyBinding) string {
+	var parts []string
+	for _, modifier := range []struct {
+		flag fyne.KeyModifier
+		name string
+	}{{fyne.KeyModifierControl, "CTRL"}, {fyne.KeyModifierAlt, "ALT"}, {fyne.KeyModifierShift, "SHIFT"}, {fyne.KeyModifierSuper, "LOGO"}} {
+		if binding.Modifiers&modifier.flag != 0 {
+			parts = append(parts, modifier.name)
+		}
+	}
+	return strings.Join(append(parts, keysymName(binding.Key)), "+")
+}
+
+// keysymNames are the X keysym names for keys Fyne spells differently
+var keysymNames = map[fyne.KeyName]string{
+	fyne.KeySpace: "space", fyne.KeyComma: "comma", fyne.KeyPeriod: "period", fyne.KeySlash: "slash",
+	fyne.KeyMinus: "minus", fyne.KeyEqual: "equal", fyne.KeySemicolon: "semicolon",
+	fyne.KeyLeftBracket: "bracketleft", fyne.KeyRightBracket: "bracketright", fyne.KeyApostrophe: "apostrophe",
+	fyne.KeyBackslash: "backslash", fyne.KeyBackTick: "grave", fyne.KeyReturn: "Return", fyne.KeyEscape: "Escape",
+	fyne.KeyTab: "Tab", fyne.KeyBackspace: "BackSpace", fyne.KeyDelete: "Delete", fyne.KeyHome: "Home",
+	fyne.KeyEnd: "End", fyne.KeyPageUp: "Prior", fyne.KeyPageDown: "Next",
+	fyne.KeyUp: "Up", fyne.KeyDown: "Down", fyne.KeyLeft: "Left", fyne.KeyRight: "Right",
+}
+
+// keysymName is the X keysym name for a key
+func keysymName(key fyne.KeyName) string {
+	if name, ok := keysymNames[key]; ok {
+		return name
+	}
+	if len(key) == 1 {
+		return strings.ToLower(string(key)) // Letters and digits
+	}
+	return string(key) // F1..F12
+}
+
+// keysymValues are the X keysyms for named keys (letters, digits and
+// punctuation are their ASCII codes)
+var keysymValues = map[string]xproto.Keysym{
+	"space": ' ', "comma": ',', "period": '.', "slash": '/', "minus": '-', "equal": '=', "semicolon": ';',
+	"bracketleft": '[', "bracketright": ']', "apostrophe": '\'', "backslash": '\\', "grave": '`',
+	"Return": 0xff0d, "Escape": 0xff1b, "Tab": 0xff09, "BackSpace": 0xff08, "Delete": 0xffff,
+	"Home": 0xff50, "End": 0xff57, "Prior": 0xff55, "Next": 0xff56,
+	"Left": 0xff51, "Up": 0xff52, "Right": 0xff53, "Down": 0xff54,
+}
+
+// keysym is the X keysym for a key
+func keysym(key fyne.KeyName) xproto.Keysym {
+	name := keysymName(key)
+	if value, ok := keysymValues[name]; ok {
+		return value
+	}
+	if len(name) == 1 {
+		return xproto.Keysym(name[0])
+	}
+	var n int
+	fmt.Sscanf(name, "F%d", &n)
+	return xproto.Keysym(0xffbe + n - 1) // F1 is 0xffbe
+}
+
+// x11Modifiers is the X modifier mask for a binding
+func x11Modifiers(modifiers fyne.KeyModifier) uint16 {
+	var mask uint16
+	if modifiers&fyne.KeyModifierShift != 0 {
+		mask |= xproto.ModMaskShift
+	}
+	if modifiers&fyne.KeyModifierControl != 0 {
+		mask |= xproto.ModMaskControl
+	}
+	if modifiers&fyne.KeyModifierAlt != 0 {
+		mask |= xproto.ModMask1
+	}
+	if modifiers&fyne.KeyModifierSuper != 0 {
+		mask |= xproto.ModMask4
+	}
+	return mask
+}
+
+// x11Hotkeys holds key grabs on the root window
+type x11Hotkeys struct {
+	conn *xgb.Conn
+}
+
+// x11LockMasks are Caps Lock and Num Lock, which mustn't stop a shortcut working
+var x11LockMasks = []uint16{0, xproto.ModMaskLock, xproto.ModMask2, xproto.ModMaskLock | xproto.ModMask2}
+
+// grabX11Hotkeys grabs the shortcuts on the X root window and calls press
+// when one is used
+func grabX11Hotkeys(bindings map[string]keyBinding, press func(string)) (*x11Hotkeys, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, err
+	}
+	setup := xproto.Setup(conn)
+	root := setup.DefaultScreen(conn).Root
+	mapping, err := xproto.GetKeyboardMapping(conn, setup.MinKeycode, byte(setup.MaxKeycode-setup.MinKeycode+1)).Reply()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	type grab struct {
+		keycode   xproto.Keycode
+		modifiers uint16
+	}
+	grabs := map[grab]string{}
+	for action, binding := range bindings {
+		keycode, ok := findKeycode(mapping, setup.MinKeycode, keysym(binding.Key))
+		if !ok {
+			log.Printf("hotkeys: global %s: no %s key on this keyboard", action, binding.Key)
+			continue
+		}
+		modifiers := x11Modifiers(binding.Modifiers)
+		grabbed := true
+		for _, locks := range x11LockMasks {
+			err := xproto.GrabKeyChecked(conn, true, root, modifiers|locks, keycode, xproto.GrabModeAsync, xproto.GrabModeAsync).Check()
+			if err != nil {
+				log.Printf("hotkeys: global %s: the key is taken by another program", action)
+				grabbed = false
+				break
+			}
+		}
+		if grabbed {
+			grabs[grab{keycode, modifiers}] = action
+		}
+	}
+
+	go func() {
+		for {
+			event, err := conn.WaitForEvent()
+			if event == nil && err == nil {
+				return // Connection closed
+			}
+			if keyPress, ok := event.(xproto.KeyPressEvent); ok {
+				modifiers := keyPress.State &^ (xproto.ModMaskLock | xproto.ModMask2)
+				if action, ok := grabs[grab{keyPress.Detail, modifiers}]; ok {
+					press(action)
+				}
+			}
+		}
+	}()
+	return &x11Hotkeys{conn: conn}, nil
+}
+
+// findKeycode looks a keysym up in the keyboard mapping
+func findKeycode(mapping *xproto.GetKeyboardMappingReply, minKeycode xproto.Keycode, sym xproto.Keysym) (xproto.Keycode, bool) {
+	perKeycode := int(mapping.KeysymsPerKeycode)
+	for i, candidate := range mapping.Keysyms {
+		if candidate == sym || (sym >= 'a' && sym <= 'z' && candidate == sym-'a'+'A') {
+			return minKeycode + xproto.Keycode(i/perKeycode), true
+		}
+	}
+	return 0, false
+}
+
+// Close releases the grabs
+func (x *x11Hotkeys) Close() error {
+	x.conn.Close()
+	return nil
+}
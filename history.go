@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HistoryKind says what a history record is about
+type HistoryKind string
+
+const (
+	HistoryCompleted HistoryKind = "completed" // Session ran down to zero
+	HistorySkipped   HistoryKind = "skipped"   // Session was skipped part way
+	HistoryLocked    HistoryKind = "locked"    // Screen was locked (Note says what we did)
+	HistorySuspended HistoryKind = "suspended" // Machine slept (Note says what we did)
+)
+
+// HistoryRecord is one line of the history log
+type HistoryRecord struct {
+	ID          string      `json:"id"`
+	Kind        HistoryKind `json:"kind"`
+	SessionType SessionType `json:"session_type,omitempty"`
+	SessionNum  int         `json:"session_num,omitempty"`
+	Task        string      `json:"task,omitempty"`
+	Start       time.Time   `json:"start"`
+	End         time.Time   `json:"end"`
+	Duration    int         `json:"duration,omitempty"` // Planned length in seconds
+	Elapsed     int         `json:"elapsed,omitempty"`  // Seconds actually counted down
+	Note        string      `json:"note,omitempty"`
+}
+
+// HistoryLog appends records to a JSON lines file. History used to be kept
+// this way; it's now only read to move old logs into the history database.
+type HistoryLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewHistoryLog uses (and later creates) the file at path
+func NewHistoryLog(path string) *HistoryLog {
+	return &HistoryLog{path: path}
+}
+
+// getHistoryPath returns where the old history log lived
+func getHistoryPath() string {
+	return filepath.Join(getProfileDir(), "history.jsonl")
+}
+
+// setupHistory opens the history store and records finished and skipped sessions
+func setupHistory() {
+	historyStore = openHistoryStore()
+	recorder := &historyRecorder{store: historyStore}
+	onLifecycleEvent(recorder.HandleEvent)
+}
+
+// Append adds a record, filling in its ID if it has none
+func (h *HistoryLog) Append(record HistoryRecord) error {
+	if record.ID == "" {
+		record.ID = newRecordID()
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Records reads the whole log, skipping lines that don't parse
+func (h *HistoryLog) Records() ([]HistoryRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil // No history yet
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("history: skipping bad line in %s: %v", h.path, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// recordHistory appends to the global store, logging failures
+func recordHistory(record HistoryRecord) {
+	if historyStore == nil {
+		return
+	}
+	if err := historyStore.Append(record); err != nil {
+		log.Printf("history: %v", err)
+	}
+}
+
+// historyRecorder turns lifecycle events into session records
+type historyRecorder struct {
+	store   HistoryRepository
+	started time.Time // When the current session first started
+}
+
+// HandleEvent records finished and skipped sessions (timer goroutine)
+func (r *historyRecorder) HandleEvent(sessionEvent SessionEvent) {
+	switch sessionEvent.Event {
+	case LifecycleSessionStart:
+		if !sessionEvent.Resumed || r.started.IsZero() {
+			r.started = sessionEvent.Time
+		}
+	case LifecycleSessionFinish, LifecycleSessionSkip:
+		kind := HistoryCompleted
+		if sessionEvent.Event == LifecycleSessionSkip {
+			kind = HistorySkipped
+		}
+		elapsed := sessionEvent.Duration - sessionEvent.Remaining
+		start := r.started
+		if start.IsZero() {
+			// Never saw it start (e.g. skipped before starting) - assume it just ran
+			start = sessionEvent.Time.Add(-time.Duration(elapsed) * time.Second)
+		}
+		r.started = time.Time{}
+
+		if err := r.store.Append(HistoryRecord{
+			Kind:        kind,
+			SessionType: sessionEvent.SessionType,
+			SessionNum:  sessionEvent.SessionNum,
+			Task:        sessionEvent.Task,
+			Start:       start,
+			End:         sessionEvent.Time,
+			Duration:    sessionEvent.Duration,
+			Elapsed:     elapsed,
+		}); err != nil {
+			log.Printf("history: %v", err)
+		}
+	case LifecycleSessionChange:
+		r.started = time.Time{}
+	}
+}
+
+// newRecordID returns a random ID that stays with the record forever
+func newRecordID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryLogAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := NewHistoryLog(path)
+
+	records, err := h.Records()
+	if err != nil || len(records) != 0 {
+		t.Fatalf("Missing log should read as empty, got %v %v", records, err)
+	}
+
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	h.Append(HistoryRecord{Kind: HistoryCompleted, SessionType: SessionWork, Start: start, End: start.Add(25 * time.Minute)})
+	h.Append(HistoryRecord{Kind: HistorySkipped, SessionType: SessionShortBreak, Start: start})
+
+	// A torn line from a crash shouldn't lose the rest
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString("{\"kind\": \"compl\n")
+	file.Close()
+	h.Append(HistoryRecord{Kind: HistoryLocked, Note: "paused"})
+
+	records, err = h.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[0].ID == "" || records[0].ID == records[1].ID {
+		t.Error("Records should get unique IDs")
+	}
+	if !records[0].End.Equal(start.Add(25*time.Minute)) || records[2].Note != "paused" {
+		t.Errorf("Records did not round-trip: %+v", records)
+	}
+}
+
+func TestHistoryRecorder(t *testing.T) {
+	h := NewHistoryDB(filepath.Join(t.TempDir(), "history.db"))
+	recorder := &historyRecorder{store: h}
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	recorder.HandleEvent(SessionEvent{Event: LifecycleSessionStart, Time: start, SessionType: SessionWork})
+	recorder.HandleEvent(SessionEvent{Event: LifecycleSessionStart, Time: start.Add(10 * time.Minute), Resumed: true})
+	recorder.HandleEvent(SessionEvent{
+		Event: LifecycleSessionFinish, Time: start.Add(30 * time.Minute),
+		SessionType: SessionWork, SessionNum: 1, Task: "docs", Duration: 1500,
+	})
+	recorder.HandleEvent(SessionEvent{Event: LifecycleSessionChange})
+	recorder.HandleEvent(SessionEvent{
+		Event: LifecycleSessionSkip, Time: start.Add(31 * time.Minute),
+		SessionType: SessionShortBreak, Duration: 300, Remaining: 240,
+	})
+
+	records, _ := h.Query(HistoryQuery{})
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	work := records[0]
+	if work.Kind != HistoryCompleted || !work.Start.Equal(start) || work.Elapsed != 1500 || work.Task != "docs" {
+		t.Errorf("Resuming should keep the first start time: %+v", work)
+	}
+	skipped := records[1]
+	if skipped.Kind != HistorySkipped || skipped.Elapsed != 60 || !skipped.Start.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("Unexpected skipped record %+v", skipped)
+	}
+}
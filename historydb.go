@@ -0,0 +1,464 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HistoryRepository stores session history and keeps running totals per day
+// and per task, so stats don't have to re-read every record
+type HistoryRepository interface {
+	Append(records ...HistoryRecord) error             // Records already stored (same ID) are skipped
+	Query(query HistoryQuery) ([]HistoryRecord, error) // Oldest first
+	DailyTotals(from, to time.Time) ([]DailyTotal, error)
+	Tasks() ([]TaskTotal, error) // Most focus first
+	Compact() error              // Rebuild the totals and reclaim free space
+
+	// Since returns up to limit records in the order they were added, after
+	// cursor, and the cursor to pass next time
+	Since(cursor uint64, limit int) ([]HistoryRecord, uint64, error)
+}
+
+// HistoryQuery picks records; zero fields match everything
+type HistoryQuery struct {
+	From, To    time.Time // Records that started in [From, To)
+	Task        string
+	SessionType SessionType
+	Kinds       []HistoryKind
+}
+
+// DailyTotal sums up one day's sessions
+type DailyTotal struct {
+	Day    string       `json:"day"`    // YYYY-MM-DD in local time
+	Focus  reportTotals `json:"focus"`  // Work sessions
+	Breaks reportTotals `json:"breaks"` // Everything else
+}
+
+// TaskTotal sums up the work sessions spent on one task
+type TaskTotal struct {
+	Task     string       `json:"task"`
+	Focus    reportTotals `json:"focus"`
+	LastUsed time.Time    `json:"last_used"`
+}
+
+// Bucket names in the history database
+var (
+	recordsBucket = []byte("records") // start time + ID -> record JSON
+	idsBucket     = []byte("ids")     // ID -> records key
+	dailyBucket   = []byte("daily")   // YYYY-MM-DD -> DailyTotal JSON
+	tasksBucket   = []byte("tasks")   // task -> TaskTotal JSON
+	addedBucket   = []byte("added")   // sequence -> records key, in the order added
+)
+
+// HistoryDB is a HistoryRepository in a bbolt file. The file is only open
+// while a call runs, so CLI exports and imports work alongside the GUI.
+type HistoryDB struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Global history store (nil until setupHistory)
+var historyStore HistoryRepository
+
+// NewHistoryDB uses (and later creates) the database at path
+func NewHistoryDB(path string) *HistoryDB {
+	return &HistoryDB{path: path}
+}
+
+// getHistoryDBPath returns where the active profile's history database lives
+func getHistoryDBPath() string {
+	return filepath.Join(getProfileDir(), "history.db")
+}
+
+// openHistoryStore returns the history database, moving an old history.jsonl
+// into it the first time
+func openHistoryStore() *HistoryDB {
+	db := NewHistoryDB(getHistoryDBPath())
+	if err := importHistoryLog(db, getHistoryPath()); err != nil {
+		log.Printf("history: importing %s: %v", getHistoryPath(), err)
+	}
+	return db
+}
+
+// importHistoryLog copies a JSON lines log into the store and renames it, so
+// it's only done once
+func importHistoryLog(store HistoryRepository, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil // Nothing to import
+	}
+	records, err := NewHistoryLog(path).Records()
+	if err != nil {
+		return err
+	}
+	if err := store.Append(records...); err != nil {
+		return err
+	}
+	return os.Rename(path, path+".imported")
+}
+
+// open runs fn with the database open
+func (h *HistoryDB) open(fn func(db *bolt.DB) error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	db, err := bolt.Open(h.path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", h.path, err)
+	}
+	defer db.Close()
+	return fn(db)
+}
+
+// update runs fn in a write transaction with all the buckets created
+func (h *HistoryDB) update(fn func(tx *bolt.Tx) error) error {
+	return h.open(func(db *bolt.DB) error {
+		return db.Update(func(tx *bolt.Tx) error {
+			backfill := tx.Bucket(addedBucket) == nil
+			for _, name := range [][]byte{recordsBucket, idsBucket, dailyBucket, tasksBucket, addedBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			if backfill {
+				// Databases from before the added order: take them in start order
+				err := tx.Bucket(recordsBucket).ForEach(func(key, _ []byte) error {
+					return addedInOrder(tx, key)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return fn(tx)
+		})
+	})
+}
+
+// view runs fn in a read transaction. Buckets may be missing on a new database.
+func (h *HistoryDB) view(fn func(tx *bolt.Tx) error) error {
+	return h.open(func(db *bolt.DB) error {
+		return db.View(fn)
+	})
+}
+
+// recordKey orders records by start time; the ID keeps equal starts apart
+func recordKey(record HistoryRecord) []byte {
+	return append(timeKey(record.Start), record.ID...)
+}
+
+// timeKey is the smallest record key at or after t. Times before 1970 (or
+// missing) sort first.
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	if t.After(time.Unix(0, 0)) {
+		binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	}
+	return key
+}
+
+// Append stores records in one transaction, updating the totals
+func (h *HistoryDB) Append(records ...HistoryRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return h.update(func(tx *bolt.Tx) error {
+		for _, record := range records {
+			if record.ID == "" {
+				record.ID = newRecordID()
+			}
+			if tx.Bucket(idsBucket).Get([]byte(record.ID)) != nil {
+				continue // Already have it
+			}
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			key := recordKey(record)
+			if err := tx.Bucket(recordsBucket).Put(key, data); err != nil {
+				return err
+			}
+			if err := tx.Bucket(idsBucket).Put([]byte(record.ID), key); err != nil {
+				return err
+			}
+			if err := addedInOrder(tx, key); err != nil {
+				return err
+			}
+			if err := addToTotals(tx, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// seqKey orders sequence-numbered entries
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// addedInOrder notes that the record at key was just added
+func addedInOrder(tx *bolt.Tx, key []byte) error {
+	added := tx.Bucket(addedBucket)
+	seq, err := added.NextSequence()
+	if err != nil {
+		return err
+	}
+	return added.Put(seqKey(seq), key)
+}
+
+// addToTotals counts a record in its day's and task's totals (locks and
+// suspends don't count, same as the reports)
+func addToTotals(tx *bolt.Tx, record HistoryRecord) error {
+	if record.Kind != HistoryCompleted && record.Kind != HistorySkipped {
+		return nil
+	}
+
+	day := DailyTotal{Day: record.Start.Local().Format("2006-01-02")}
+	if err := getJSON(tx.Bucket(dailyBucket), day.Day, &day); err != nil {
+		return err
+	}
+	if record.SessionType == SessionWork {
+		day.Focus.add(record)
+	} else {
+		day.Breaks.add(record)
+	}
+	if err := putJSON(tx.Bucket(dailyBucket), day.Day, day); err != nil {
+		return err
+	}
+
+	if record.SessionType != SessionWork || record.Task == "" {
+		return nil
+	}
+	task := TaskTotal{Task: record.Task}
+	if err := getJSON(tx.Bucket(tasksBucket), task.Task, &task); err != nil {
+		return err
+	}
+	task.Focus.add(record)
+	if record.Start.After(task.LastUsed) {
+		task.LastUsed = record.Start
+	}
+	return putJSON(tx.Bucket(tasksBucket), task.Task, task)
+}
+
+// getJSON reads key into value, leaving value alone if it isn't there
+func getJSON(bucket *bolt.Bucket, key string, value interface{}) error {
+	data := bucket.Get([]byte(key))
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, value)
+}
+
+// putJSON stores value under key
+func putJSON(bucket *bolt.Bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), data)
+}
+
+// matches reports whether a record passes the query's filters
+func (q HistoryQuery) matches(record HistoryRecord) bool {
+	if q.Task != "" && record.Task != q.Task {
+		return false
+	}
+	if q.SessionType != "" && record.SessionType != q.SessionType {
+		return false
+	}
+	if len(q.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range q.Kinds {
+		if record.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the matching records, oldest first
+func (h *HistoryDB) Query(query HistoryQuery) ([]HistoryRecord, error) {
+	var records []HistoryRecord
+	err := h.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(recordsBucket)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		key, data := cursor.First()
+		if !query.From.IsZero() {
+			key, data = cursor.Seek(timeKey(query.From))
+		}
+		for ; key != nil; key, data = cursor.Next() {
+			var record HistoryRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			if !query.To.IsZero() && !record.Start.Before(query.To) {
+				break
+			}
+			if query.matches(record) {
+				records = append(records, record)
+			}
+		}
+		return nil
+	})
+	return records, err
+}
+
+// Since returns records added after cursor, oldest addition first
+func (h *HistoryDB) Since(cursor uint64, limit int) ([]HistoryRecord, uint64, error) {
+	var records []HistoryRecord
+	next := cursor
+	// A write, since old databases get their added order on first use
+	err := h.update(func(tx *bolt.Tx) error {
+		byKey := tx.Bucket(recordsBucket)
+		added := tx.Bucket(addedBucket).Cursor()
+		for seq, key := added.Seek(seqKey(cursor + 1)); seq != nil && len(records) < limit; seq, key = added.Next() {
+			next = binary.BigEndian.Uint64(seq)
+			var record HistoryRecord
+			if err := json.Unmarshal(byKey.Get(key), &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, next, err
+}
+
+// DailyTotals returns the totals for days in [from, to), zero times leaving
+// that end open. Days without sessions are left out.
+func (h *HistoryDB) DailyTotals(from, to time.Time) ([]DailyTotal, error) {
+	var totals []DailyTotal
+	err := h.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(dailyBucket)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		key, data := cursor.First()
+		if !from.IsZero() {
+			key, data = cursor.Seek([]byte(from.Local().Format("2006-01-02")))
+		}
+		last := ""
+		if !to.IsZero() {
+			last = to.Local().Format("2006-01-02")
+		}
+		for ; key != nil; key, data = cursor.Next() {
+			if last != "" && string(key) >= last {
+				break
+			}
+			var total DailyTotal
+			if err := json.Unmarshal(data, &total); err != nil {
+				return err
+			}
+			totals = append(totals, total)
+		}
+		return nil
+	})
+	return totals, err
+}
+
+// Tasks returns the totals per task, most focus first
+func (h *HistoryDB) Tasks() ([]TaskTotal, error) {
+	var tasks []TaskTotal
+	err := h.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tasksBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var task TaskTotal
+			if err := json.Unmarshal(data, &task); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Focus.Seconds > tasks[j].Focus.Seconds })
+	return tasks, err
+}
+
+// Compact recounts the totals from the records (fixing days that moved with
+// the time zone) and copies the database into a fresh file without free pages
+func (h *HistoryDB) Compact() error {
+	err := h.update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{dailyBucket, tasksBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(recordsBucket).ForEach(func(_, data []byte) error {
+			var record HistoryRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			return addToTotals(tx, record)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	compactPath := h.path + ".compact"
+	os.Remove(compactPath)
+	err = h.open(func(src *bolt.DB) error {
+		dst, err := bolt.Open(compactPath, 0644, nil)
+		if err != nil {
+			return err
+		}
+		if err := bolt.Compact(dst, src, 64*1024*1024); err != nil {
+			dst.Close()
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+		// Still holding the lock on the old file, so nobody writes in between
+		return os.Rename(compactPath, h.path)
+	})
+	if err != nil {
+		os.Remove(compactPath)
+	}
+	return err
+}
+
+// runCompactCommand compacts the history database
+func runCompactCommand(args []string) int {
+	flags := flag.NewFlagSet("compact", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	// The GUI opens the database between our lock and the rename otherwise
+	if _, err := queryRunningInstance("ping"); err == nil {
+		fmt.Fprintln(os.Stderr, "gomodoro compact: quit the running GoModoro first")
+		return 1
+	}
+
+	path := getHistoryDBPath()
+	before, _ := os.Stat(path)
+	if err := openHistoryStore().Compact(); err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro compact:", err)
+		return 1
+	}
+	if after, err := os.Stat(path); err == nil && before != nil {
+		fmt.Printf("Compacted %s: %d KiB -> %d KiB\n", path, before.Size()/1024, after.Size()/1024)
+	}
+	return 0
+}
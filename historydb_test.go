@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestHistoryDB returns a database holding testHistory()
+func newTestHistoryDB(t *testing.T) *HistoryDB {
+	t.Helper()
+	db := NewHistoryDB(filepath.Join(t.TempDir(), "history.db"))
+	if err := db.Append(testHistory()...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// recordIDs lists the IDs, for compact comparisons
+func recordIDs(records []HistoryRecord) string {
+	ids := ""
+	for _, record := range records {
+		ids += record.ID + " "
+	}
+	return ids
+}
+
+func TestHistoryDBQuery(t *testing.T) {
+	db := newTestHistoryDB(t)
+	from := time.Date(2024, 3, 4, 9, 30, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name  string
+		query HistoryQuery
+		want  string
+	}{
+		{"everything", HistoryQuery{}, "a1 b2 c3 d4 e5 "},
+		{"date range", HistoryQuery{From: from, To: to}, "c3 d4 "},
+		{"task", HistoryQuery{Task: "Write docs; then, ship"}, "a1 c3 "},
+		{"session type", HistoryQuery{SessionType: SessionShortBreak}, "b2 "},
+		{"kinds", HistoryQuery{SessionType: SessionWork, Kinds: []HistoryKind{HistoryCompleted}}, "a1 e5 "},
+	} {
+		records, err := db.Query(tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := recordIDs(records); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestHistoryDBSkipsKnownRecords(t *testing.T) {
+	db := newTestHistoryDB(t)
+	db.Append(testHistory()...)
+	db.Append(HistoryRecord{Kind: HistoryCompleted, SessionType: SessionWork, Start: time.Now(), Elapsed: 60})
+
+	records, _ := db.Query(HistoryQuery{})
+	if len(records) != 6 || records[5].ID == "" {
+		t.Errorf("Expected the 5 records once plus one with a fresh ID, got %q", recordIDs(records))
+	}
+	tasks, _ := db.Tasks()
+	if len(tasks) != 1 || tasks[0].Focus.Completed != 1 || tasks[0].Focus.Skipped != 1 {
+		t.Errorf("Re-appending shouldn't double count, got %+v", tasks)
+	}
+}
+
+func TestHistoryDBTotals(t *testing.T) {
+	db := newTestHistoryDB(t)
+	day := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC).Local().Format("2006-01-02")
+
+	totals, err := db.DailyTotals(time.Time{}, time.Time{})
+	if err != nil || len(totals) != 1 {
+		t.Fatalf("Expected one day, got %+v %v", totals, err)
+	}
+	if totals[0].Day != day || totals[0].Focus.Seconds != 3600 || totals[0].Focus.Completed != 2 ||
+		totals[0].Focus.Skipped != 1 || totals[0].Breaks.Seconds != 300 {
+		t.Errorf("Unexpected totals %+v", totals[0])
+	}
+	if later, _ := db.DailyTotals(time.Date(2024, 3, 6, 0, 0, 0, 0, time.Local), time.Time{}); len(later) != 0 {
+		t.Errorf("Range should leave the day out, got %+v", later)
+	}
+
+	tasks, _ := db.Tasks()
+	if len(tasks) != 1 || tasks[0].Task != "Write docs; then, ship" || tasks[0].Focus.Seconds != 2100 {
+		t.Errorf("Unexpected task totals %+v", tasks)
+	}
+}
+
+func TestHistoryDBCompact(t *testing.T) {
+	db := newTestHistoryDB(t)
+	before, _ := db.DailyTotals(time.Time{}, time.Time{})
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := db.DailyTotals(time.Time{}, time.Time{})
+	if len(after) != 1 || after[0] != before[0] {
+		t.Errorf("Totals should be rebuilt the same, got %+v want %+v", after, before)
+	}
+	if records, _ := db.Query(HistoryQuery{}); len(records) != 5 {
+		t.Errorf("Compacting lost records: %q", recordIDs(records))
+	}
+	if _, err := os.Stat(db.path + ".compact"); err == nil {
+		t.Error("Compaction file left behind")
+	}
+}
+
+func TestImportHistoryLog(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "history.jsonl")
+	old := NewHistoryLog(logPath)
+	for _, record := range testHistory() {
+		old.Append(record)
+	}
+
+	db := NewHistoryDB(filepath.Join(dir, "history.db"))
+	if err := importHistoryLog(db, logPath); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := db.Query(HistoryQuery{}); len(records) != 5 {
+		t.Errorf("Expected the old log in the database, got %q", recordIDs(records))
+	}
+	if _, err := os.Stat(logPath + ".imported"); err != nil {
+		t.Error("Old log should be renamed so it's only imported once")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHookTimeout applies when a hook doesn't set its own
+const defaultHookTimeout = 10 * time.Second
+
+// Hook is a user shell command run on a lifecycle event
+type Hook struct {
+	Event          LifecycleEvent `json:"event"`
+	Command        string         `json:"command"`
+	TimeoutSeconds int            `json:"timeout_seconds,omitempty"` // Default 10
+}
+
+// setupHooks subscribes the configured hooks to lifecycle events
+func setupHooks() {
+	onLifecycleEvent(runHooksForEvent)
+}
+
+// runHooksForEvent starts every hook for the event without waiting for them
+func runHooksForEvent(sessionEvent SessionEvent) {
+	for _, hook := range DefaultSettings.Hooks {
+		if hook.Event != sessionEvent.Event || strings.TrimSpace(hook.Command) == "" {
+			continue
+		}
+		go func(hook Hook) {
+			if err := runHook(hook, sessionEvent); err != nil {
+				log.Printf("hooks: %s hook %q failed: %v", hook.Event, hook.Command, err)
+			}
+		}(hook)
+	}
+}
+
+// hookEnvironment exposes the session details as GOMODORO_* variables
+func hookEnvironment(sessionEvent SessionEvent) []string {
+	return []string{
+		"GOMODORO_EVENT=" + string(sessionEvent.Event),
+		"GOMODORO_SESSION_TYPE=" + string(sessionEvent.SessionType),
+		"GOMODORO_SESSION_NUM=" + strconv.Itoa(sessionEvent.SessionNum),
+		"GOMODORO_DURATION=" + strconv.Itoa(sessionEvent.Duration),
+		"GOMODORO_REMAINING=" + strconv.Itoa(sessionEvent.Remaining),
+		"GOMODORO_TASK=" + sessionEvent.Task,
+		"GOMODORO_CYCLE_INDEX=" + strconv.Itoa(sessionEvent.CycleIndex),
+		"GOMODORO_CYCLE_LENGTH=" + strconv.Itoa(sessionEvent.CycleLength),
+		"GOMODORO_RESUMED=" + strconv.FormatBool(sessionEvent.Resumed),
+	}
+}
+
+// runHook runs one hook to completion (or timeout) with the event on stdin
+func runHook(hook Hook, sessionEvent SessionEvent) error {
+	timeout := defaultHookTimeout
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+
+	payload, err := json.Marshal(sessionEvent)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Env = append(os.Environ(), hookEnvironment(sessionEvent)...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Don't let a background child holding the pipes keep us waiting past the timeout
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return ctx.Err()
+	}
+	if err != nil {
+		if out := strings.TrimSpace(output.String()); out != "" {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
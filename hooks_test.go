@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHookPassesEnvAndStdin(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env")
+	stdinFile := filepath.Join(dir, "stdin")
+
+	hook := Hook{
+		Event:   LifecycleSessionStart,
+		Command: "echo \"$GOMODORO_SESSION_TYPE $GOMODORO_SESSION_NUM $GOMODORO_TASK\" > " + envFile + "; cat > " + stdinFile,
+	}
+	sessionEvent := SessionEvent{
+		Event:       LifecycleSessionStart,
+		SessionType: SessionWork,
+		SessionNum:  3,
+		Duration:    1500,
+		Task:        "write docs",
+	}
+
+	if err := runHook(hook, sessionEvent); err != nil {
+		t.Fatalf("runHook failed: %v", err)
+	}
+
+	env, _ := os.ReadFile(envFile)
+	if strings.TrimSpace(string(env)) != "work 3 write docs" {
+		t.Errorf("Unexpected environment %q", env)
+	}
+
+	var payload SessionEvent
+	stdin, _ := os.ReadFile(stdinFile)
+	if err := json.Unmarshal(stdin, &payload); err != nil {
+		t.Fatalf("stdin is not JSON: %v (%q)", err, stdin)
+	}
+	if payload.Duration != 1500 || payload.Task != "write docs" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+func TestRunHookFailureIncludesOutput(t *testing.T) {
+	err := runHook(Hook{Command: "echo nope >&2; exit 3"}, SessionEvent{})
+	if err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Expected failure with output, got %v", err)
+	}
+}
+
+func TestRunHookTimeout(t *testing.T) {
+	start := time.Now()
+	err := runHook(Hook{Command: "sleep 5", TimeoutSeconds: 1}, SessionEvent{})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 4*time.Second {
+		t.Errorf("Hook was not killed at its timeout (took %v)", time.Since(start))
+	}
+}
+
+func TestRunHooksForEventDoesNotBlock(t *testing.T) {
+	originalSettings := DefaultSettings
+	defer func() {
+		DefaultSettings = originalSettings
+	}()
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	DefaultSettings.Hooks = []Hook{
+		{Event: LifecycleSessionPause, Command: "sleep 1; touch " + marker},
+		{Event: LifecycleSessionStart, Command: "touch " + marker + ".wrong"},
+	}
+
+	start := time.Now()
+	runHooksForEvent(SessionEvent{Event: LifecycleSessionPause})
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("runHooksForEvent blocked on the hook")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(marker); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error("Pause hook never ran")
+	}
+	if _, err := os.Stat(marker + ".wrong"); err == nil {
+		t.Error("Start hook should not run for a pause event")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// HotkeySettings binds actions to keys. Window keys work while GoModoro has
+// focus (and the task box doesn't); global ones work from any window.
+// Set an action to "" to turn its key off.
+type HotkeySettings struct {
+	Window map[string]string `json:"window"` // Action -> key, e.g. "skip": "S"
+	Global map[string]string `json:"global"` // Action -> shortcut, e.g. "start_pause": "Ctrl+Alt+P"
+}
+
+// DefaultHotkeySettings - the buttons get keys; nothing is grabbed system-wide
+var DefaultHotkeySettings = HotkeySettings{
+	Window: map[string]string{
+		"start_pause": "Space",
+		"skip":        "S",
+		"reset":       "R",
+		"next":        "N",
+		"settings":    "Comma",
+		"mini":        "M",
+	},
+	Global: map[string]string{},
+}
+
+// hotkeyActions are what keys can be bound to
+var hotkeyActions = map[string]string{
+	"start_pause": "Start or pause the timer",
+	"skip":        "Skip the current session",
+	"reset":       "Reset the current session",
+	"next":        "Move on to the next session",
+	"settings":    "Open the settings",
+	"show":        "Bring the GoModoro window up",
+	"mini":        "Switch between the full and mini window",
+}
+
+// hotkeyCommandPrefix marks a shortcut sent to the timer goroutine, which
+// decides what it means (like the button would) and runs it
+const hotkeyCommandPrefix = "hotkey:"
+
+// keyBinding is a parsed key like "Ctrl+Alt+P"
+type keyBinding struct {
+	Key       fyne.KeyName // As Fyne spells it ("Space", "P", ",")
+	Modifiers fyne.KeyModifier
+}
+
+// keyAliases are friendlier names for keys Fyne spells as symbols
+var keyAliases = map[string]fyne.KeyName{
+	"space": fyne.KeySpace, "comma": fyne.KeyComma, "period": fyne.KeyPeriod, "dot": fyne.KeyPeriod,
+	"slash": fyne.KeySlash, "minus": fyne.KeyMinus, "equal": fyne.KeyEqual, "semicolon": fyne.KeySemicolon,
+	"enter": fyne.KeyReturn, "return": fyne.KeyReturn, "escape": fyne.KeyEscape, "esc": fyne.KeyEscape,
+	"tab": fyne.KeyTab, "backspace": fyne.KeyBackspace, "delete": fyne.KeyDelete,
+	"home": fyne.KeyHome, "end": fyne.KeyEnd, "pageup": fyne.KeyPageUp, "pagedown": fyne.KeyPageDown,
+	"up": fyne.KeyUp, "down": fyne.KeyDown, "left": fyne.KeyLeft, "right": fyne.KeyRight,
+}
+
+// keyModifiers are the modifier names bindings may use
+var keyModifiers = map[string]fyne.KeyModifier{
+	"ctrl": fyne.KeyModifierControl, "control": fyne.KeyModifierControl,
+	"alt": fyne.KeyModifierAlt, "shift": fyne.KeyModifierShift,
+	"super": fyne.KeyModifierSuper, "meta": fyne.KeyModifierSuper, "win": fyne.KeyModifierSuper,
+}
+
+// parseKeyBinding reads "Ctrl+Alt+P", "Space", "comma", "F5"...
+func parseKeyBinding(text string) (keyBinding, error) {
+	parts := strings.Split(text, "+")
+	var binding keyBinding
+	for _, part := range parts[:len(parts)-1] {
+		modifier, ok := keyModifiers[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return binding, fmt.Errorf("key %q: unknown modifier %q (Ctrl, Alt, Shift or Super)", text, part)
+		}
+		binding.Modifiers |= modifier
+	}
+
+	key := strings.TrimSpace(parts[len(parts)-1])
+	switch {
+	case key == "":
+		return binding, fmt.Errorf("key %q: no key", text)
+	case keyAliases[strings.ToLower(key)] != "":
+		binding.Key = keyAliases[strings.ToLower(key)]
+	case len(key) == 1 && (key[0] >= 'a' && key[0] <= 'z' || key[0] >= 'A' && key[0] <= 'Z' || key[0] >= '0' && key[0] <= '9'):
+		binding.Key = fyne.KeyName(strings.ToUpper(key))
+	case len(key) == 1 && strings.ContainsAny(key, ",./-=;[]'`\\"):
+		binding.Key = fyne.KeyName(key)
+	case (key[0] == 'f' || key[0] == 'F') && isFunctionKey(key[1:]):
+		binding.Key = fyne.KeyName("F" + key[1:])
+	default:
+		return binding, fmt.Errorf("key %q: unknown key %q", text, key)
+	}
+	return binding, nil
+}
+
+// isFunctionKey reports whether number is 1 to 12
+func isFunctionKey(number string) bool {
+	for n := 1; n <= 12; n++ {
+		if number == fmt.Sprint(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHotkeys reads a set of bindings, logging (and leaving out) bad ones
+func parseHotkeys(keys map[string]string, scope string) map[string]keyBinding {
+	bindings := map[string]keyBinding{}
+	actions := make([]string, 0, len(keys))
+	for action := range keys {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		if keys[action] == "" {
+			continue // Turned off
+		}
+		if hotkeyActions[action] == "" {
+			log.Printf("hotkeys: %s: unknown action %q", scope, action)
+			continue
+		}
+		binding, err := parseKeyBinding(keys[action])
+		if err != nil {
+			log.Printf("hotkeys: %s %s: %v", scope, action, err)
+			continue
+		}
+		bindings[action] = binding
+	}
+	return bindings
+}
+
+// setupWindowShortcuts binds the window keys on the main window's canvas
+func setupWindowShortcuts(canvas fyne.Canvas) {
+	plain := map[fyne.KeyName]string{}
+	for action, binding := range parseHotkeys(DefaultSettings.Hotkeys.Window, "window") {
+		action := action
+		if binding.Modifiers == 0 {
+			plain[binding.Key] = action
+			continue
+		}
+		canvas.AddShortcut(&desktop.CustomShortcut{KeyName: binding.Key, Modifier: binding.Modifiers}, func(fyne.Shortcut) {
+			runHotkey(action)
+		})
+	}
+	// Only reaches us when no widget (the task box) has the focus
+	canvas.SetOnTypedKey(func(event *fyne.KeyEvent) {
+		if action, ok := plain[event.Name]; ok {
+			runHotkey(action)
+		}
+	})
+}
+
+// runHotkey does what a key is bound to: windows on the UI goroutine, the
+// rest through the timer goroutine. Global hotkeys call it from their listener.
+func runHotkey(action string) {
+	switch action {
+	case "settings":
+		fyne.Do(showSettingsWindow)
+	case "show":
+		fyne.Do(func() {
+			if myWindow != nil {
+				showFullWindow()
+			}
+		})
+	case "mini":
+		fyne.Do(func() {
+			if myWindow != nil {
+				toggleMiniWindow()
+			}
+		})
+	default:
+		controlChannel <- hotkeyCommandPrefix + action
+	}
+}
+
+// hotkeyCommand turns a shortcut into the command its button would send,
+// as of the current state, or "" if the button is off (timer goroutine)
+func hotkeyCommand(command string) string {
+	action, ok := strings.CutPrefix(command, hotkeyCommandPrefix)
+	if !ok {
+		return command
+	}
+	switch action {
+	case "start_pause":
+		switch currentState {
+		case TimerReady, TimerPaused:
+			return "start"
+		case TimerRunning:
+			return "pause"
+		case TimerFinished:
+			return "next"
+		}
+	case "skip":
+		if currentState == TimerReady || currentState == TimerPaused {
+			return "skip" // Like the button: not mid-session
+		}
+	case "reset":
+		return "reset"
+	case "next":
+		if currentState == TimerFinished {
+			return "next"
+		}
+	}
+	return ""
+}
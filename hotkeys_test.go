@@ -0,0 +1,224 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/test"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestParseKeyBinding(t *testing.T) {
+	tests := []struct {
+		text string
+		want keyBinding
+	}{
+		{"Space", keyBinding{Key: fyne.KeySpace}},
+		{"s", keyBinding{Key: "S"}},
+		{"Comma", keyBinding{Key: fyne.KeyComma}},
+		{",", keyBinding{Key: fyne.KeyComma}},
+		{"f5", keyBinding{Key: "F5"}},
+		{"Ctrl+Alt+P", keyBinding{Key: "P", Modifiers: fyne.KeyModifierControl | fyne.KeyModifierAlt}},
+		{"super + shift + 1", keyBinding{Key: "1", Modifiers: fyne.KeyModifierSuper | fyne.KeyModifierShift}},
+	}
+	for _, test := range tests {
+		got, err := parseKeyBinding(test.text)
+		if err != nil || got != test.want {
+			t.Errorf("%q: got %+v %v, want %+v", test.text, got, err, test.want)
+		}
+	}
+	for _, bad := range []string{"", "Ctrl+", "Hyper+P", "F13", "Banana"} {
+		if _, err := parseKeyBinding(bad); err == nil {
+			t.Errorf("%q should be refused", bad)
+		}
+	}
+}
+
+func TestParseHotkeysSkipsBadAndOff(t *testing.T) {
+	bindings := parseHotkeys(map[string]string{
+		"start_pause": "Space",
+		"skip":        "",       // Turned off
+		"reset":       "Banana", // Bad key
+		"launch":      "L",      // Unknown action
+	}, "window")
+	if len(bindings) != 1 || bindings["start_pause"].Key != fyne.KeySpace {
+		t.Errorf("Expected only start_pause, got %+v", bindings)
+	}
+}
+
+func TestHotkeyCommandFollowsButtons(t *testing.T) {
+	original := currentState
+	defer func() { currentState = original }()
+	tests := []struct {
+		state, action, want string
+	}{
+		{TimerReady, "start_pause", "start"},
+		{TimerPaused, "start_pause", "start"},
+		{TimerRunning, "start_pause", "pause"},
+		{TimerFinished, "start_pause", "next"},
+		{TimerPaused, "skip", "skip"},
+		{TimerRunning, "skip", ""}, // Button's off mid-session
+		{TimerRunning, "reset", "reset"},
+		{TimerRunning, "next", ""},
+		{TimerFinished, "next", "next"},
+	}
+	for _, test := range tests {
+		currentState = test.state
+		if got := hotkeyCommand(hotkeyCommandPrefix + test.action); got != test.want {
+			t.Errorf("%s in %s: got %q, want %q", test.action, test.state, got, test.want)
+		}
+	}
+	if got := hotkeyCommand("pause"); got != "pause" {
+		t.Errorf("Other commands pass through, got %q", got)
+	}
+}
+
+// nextCommand waits for a command on the control channel
+func nextCommand(t *testing.T) string {
+	t.Helper()
+	select {
+	case command := <-controlChannel:
+		return command
+	case <-time.After(5 * time.Second):
+		t.Fatal("No command sent")
+		return ""
+	}
+}
+
+func TestWindowShortcuts(t *testing.T) {
+	newTestSessionManager(t, 2)
+	DefaultSettings.Hotkeys = HotkeySettings{Window: map[string]string{
+		"start_pause": "Space",
+		"skip":        "S",
+		"reset":       "Ctrl+R",
+		"show":        "G",
+	}}
+	myApp = test.NewApp()
+	defer func() { myApp = nil }()
+	window := test.NewWindow(nil)
+	defer window.Close()
+	myWindow = window
+	defer func() { myWindow = nil }()
+	canvas := window.Canvas()
+	setupWindowShortcuts(canvas)
+
+	canvas.OnTypedKey()(&fyne.KeyEvent{Name: fyne.KeySpace})
+	if command := nextCommand(t); command != hotkeyCommandPrefix+"start_pause" {
+		t.Errorf("Space should start or pause, got %q", command)
+	}
+	canvas.OnTypedKey()(&fyne.KeyEvent{Name: "S"})
+	if command := nextCommand(t); command != hotkeyCommandPrefix+"skip" {
+		t.Errorf("S should skip, got %q", command)
+	}
+	canvas.(fyne.Shortcutable).TypedShortcut(&desktop.CustomShortcut{KeyName: "R", Modifier: fyne.KeyModifierControl})
+	if command := nextCommand(t); command != hotkeyCommandPrefix+"reset" {
+		t.Errorf("Ctrl+R should reset, got %q", command)
+	}
+
+	canvas.OnTypedKey()(&fyne.KeyEvent{Name: "G"}) // Handled here, on the UI side
+	canvas.OnTypedKey()(&fyne.KeyEvent{Name: "N"}) // Not bound here
+	select {
+	case command := <-controlChannel:
+		t.Errorf("Window or unbound key sent %q", command)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPortalTrigger(t *testing.T) {
+	tests := map[string]string{
+		"Ctrl+Alt+P":   "CTRL+ALT+p",
+		"Super+Space":  "LOGO+space",
+		"Shift+F9":     "SHIFT+F9",
+		"Ctrl+Comma":   "CTRL+comma",
+		"Alt+PageDown": "ALT+Next",
+	}
+	for text, want := range tests {
+		binding, _ := parseKeyBinding(text)
+		if got := portalTrigger(binding); got != want {
+			t.Errorf("%s: got %q, want %q", text, got, want)
+		}
+	}
+	if keysym("P") != 'p' || keysym(fyne.KeySpace) != ' ' || keysym("F1") != 0xffbe || keysym(fyne.KeyReturn) != 0xff0d {
+		t.Error("Wrong X keysyms")
+	}
+}
+
+// mockShortcutsPortal answers like the GlobalShortcuts portal
+type mockShortcutsPortal struct {
+	conn *dbus.Conn
+
+	mu    sync.Mutex
+	bound map[string]string // Shortcut ID -> preferred trigger
+}
+
+// respond answers a request on the path the client expects
+func (p *mockShortcutsPortal) respond(sender dbus.Sender, options map[string]dbus.Variant, results map[string]dbus.Variant) dbus.ObjectPath {
+	token, _ := options["handle_token"].Value().(string)
+	path := dbus.ObjectPath(portalPath + "/request/" + strings.ReplaceAll(strings.TrimPrefix(string(sender), ":"), ".", "_") + "/" + token)
+	go p.conn.Emit(path, portalRequestInterface+".Response", uint32(0), results)
+	return path
+}
+
+func (p *mockShortcutsPortal) CreateSession(sender dbus.Sender, options map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+	return p.respond(sender, options, map[string]dbus.Variant{
+		"session_handle": dbus.MakeVariant(portalPath + "/session/test"),
+	}), nil
+}
+
+func (p *mockShortcutsPortal) BindShortcuts(sender dbus.Sender, session dbus.ObjectPath, shortcuts []struct {
+	ID      string
+	Options map[string]dbus.Variant
+}, parent string, options map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+	p.mu.Lock()
+	for _, shortcut := range shortcuts {
+		p.bound[shortcut.ID], _ = shortcut.Options["preferred_trigger"].Value().(string)
+	}
+	p.mu.Unlock()
+	return p.respond(sender, options, map[string]dbus.Variant{}), nil
+}
+
+// activate fires a shortcut as if the user pressed it
+func (p *mockShortcutsPortal) activate(session, id string) error {
+	return p.conn.Emit(portalPath, globalShortcutsInterface+".Activated",
+		dbus.ObjectPath(session), id, uint64(0), map[string]dbus.Variant{})
+}
+
+func TestPortalHotkeys(t *testing.T) {
+	address := startTestBus(t)
+	portal := &mockShortcutsPortal{conn: connectTestBus(t, address), bound: map[string]string{}}
+	if err := portal.conn.Export(portal, portalPath, globalShortcutsInterface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := portal.conn.RequestName(portalService, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("Failed to own %s: %v", portalService, err)
+	}
+
+	presses := make(chan string, 4)
+	bindings := parseHotkeys(map[string]string{"start_pause": "Ctrl+Alt+P", "show": "Super+G"}, "global")
+	hotkeys, err := bindPortalHotkeys(connectTestBus(t, address), bindings, func(action string) { presses <- action })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hotkeys.Close()
+
+	portal.mu.Lock()
+	if portal.bound["start_pause"] != "CTRL+ALT+p" || portal.bound["show"] != "LOGO+g" {
+		t.Errorf("Expected both shortcuts bound, got %v", portal.bound)
+	}
+	portal.mu.Unlock()
+
+	portal.activate(portalPath+"/session/other", "show") // Someone else's session
+	portal.activate(string(hotkeys.session), "start_pause")
+	select {
+	case action := <-presses:
+		if action != "start_pause" {
+			t.Errorf("Expected start_pause, got %q", action)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shortcut press never arrived")
+	}
+}
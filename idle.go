@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/godbus/dbus/v5"
+)
+
+// Timer commands sent by the "welcome back" dialog
+const (
+	IdleKeep    = "idle-keep"    // Count the time away as focus
+	IdleDiscard = "idle-discard" // Put the time away back on the clock
+)
+
+const idlePollInterval = 5 * time.Second
+
+// IdleSettings configures auto-pausing work sessions when nobody's at the keyboard
+type IdleSettings struct {
+	Enabled          bool `json:"enabled"`
+	ThresholdMinutes int  `json:"threshold_minutes"` // Idle this long pauses the session
+	SubtractIdle     bool `json:"subtract_idle"`     // Put the time away back on the clock
+	AskOnReturn      bool `json:"ask_on_return"`     // Ask instead of using SubtractIdle
+}
+
+// DefaultIdleSettings - off, but asks what to do with the time away once enabled
+var DefaultIdleSettings = IdleSettings{
+	Enabled:          false,
+	ThresholdMinutes: 5,
+	SubtractIdle:     true,
+	AskOnReturn:      true,
+}
+
+// IdleSource reports how long the user has been idle
+type IdleSource interface {
+	Name() string
+	IdleTime() (time.Duration, error)
+}
+
+// IdleReport goes to the timer goroutine when the user leaves or comes back
+type IdleReport struct {
+	Returned bool          // false = just went idle
+	Idle     time.Duration // Idle so far, or the whole time away on return
+}
+
+// idleChannel carries idle reports to the timer goroutine
+var idleChannel = make(chan IdleReport, 1)
+
+// Time away from a work session we auto-paused (timer goroutine only)
+var (
+	idlePaused       bool
+	idleAwaySeconds  int
+	idleSessionIndex int
+)
+
+// screenSaverIdleSource asks org.freedesktop.ScreenSaver on the session bus (KDE, Xfce...)
+type screenSaverIdleSource struct {
+	conn *dbus.Conn
+}
+
+func (s screenSaverIdleSource) Name() string { return "screensaver" }
+
+func (s screenSaverIdleSource) IdleTime() (time.Duration, error) {
+	var seconds uint32
+	obj := s.conn.Object("org.freedesktop.ScreenSaver", "/org/freedesktop/ScreenSaver")
+	if err := obj.Call("org.freedesktop.ScreenSaver.GetSessionIdleTime", 0).Store(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// logindIdleSource reads our logind session's IdleHint on the system bus
+type logindIdleSource struct {
+	conn *dbus.Conn
+}
+
+func (s logindIdleSource) Name() string { return "logind" }
+
+func (s logindIdleSource) IdleTime() (time.Duration, error) {
+	obj := s.conn.Object("org.freedesktop.login1", "/org/freedesktop/login1/session/auto")
+	hint, err := obj.GetProperty("org.freedesktop.login1.Session.IdleHint")
+	if err != nil {
+		return 0, err
+	}
+	if idle, _ := hint.Value().(bool); !idle {
+		return 0, nil
+	}
+	since, err := obj.GetProperty("org.freedesktop.login1.Session.IdleSinceHint")
+	if err != nil {
+		return 0, err
+	}
+	usec, _ := since.Value().(uint64) // Wall clock microseconds
+	return time.Since(time.UnixMicro(int64(usec))), nil
+}
+
+// findIdleSource returns the first idle source that answers
+func findIdleSource() IdleSource {
+	var candidates []IdleSource
+	if conn, err := dbus.ConnectSessionBus(); err == nil {
+		candidates = append(candidates, screenSaverIdleSource{conn})
+	}
+	if conn, err := dbus.ConnectSystemBus(); err == nil {
+		candidates = append(candidates, logindIdleSource{conn})
+	}
+	for _, source := range candidates {
+		if _, err := source.IdleTime(); err == nil {
+			return source
+		}
+	}
+	return nil
+}
+
+// IdleMonitor polls an idle source and reports when the user leaves and returns
+type IdleMonitor struct {
+	source    IdleSource
+	interval  time.Duration
+	threshold func() time.Duration
+	report    func(IdleReport)
+
+	idleSince time.Time // Zero while the user is around
+}
+
+// NewIdleMonitor makes a monitor; call Run on its own goroutine
+func NewIdleMonitor(source IdleSource, threshold func() time.Duration, report func(IdleReport)) *IdleMonitor {
+	return &IdleMonitor{
+		source:    source,
+		interval:  idlePollInterval,
+		threshold: threshold,
+		report:    report,
+	}
+}
+
+// setupIdleDetection starts watching for idleness if an idle source is available
+func setupIdleDetection() {
+	source := findIdleSource()
+	if source == nil {
+		log.Printf("idle: no idle source found, idle detection disabled")
+		return
+	}
+	monitor := NewIdleMonitor(source,
+		func() time.Duration {
+			if !DefaultSettings.Idle.Enabled {
+				return 0
+			}
+			return time.Duration(DefaultSettings.Idle.ThresholdMinutes) * time.Minute
+		},
+		func(report IdleReport) { idleChannel <- report },
+	)
+	go monitor.Run()
+}
+
+// Run polls for as long as the app runs
+func (m *IdleMonitor) Run() {
+	for {
+		m.check()
+		time.Sleep(m.interval)
+	}
+}
+
+// check polls the source once and reports a change of idleness
+func (m *IdleMonitor) check() {
+	threshold := m.threshold()
+	if threshold <= 0 {
+		m.idleSince = time.Time{} // Disabled
+		return
+	}
+	idle, err := m.source.IdleTime()
+	if err != nil {
+		log.Printf("idle: %s: %v", m.source.Name(), err)
+		return
+	}
+
+	switch {
+	case m.idleSince.IsZero() && idle >= threshold:
+		m.idleSince = time.Now().Add(-idle)
+		m.report(IdleReport{Idle: idle})
+	case !m.idleSince.IsZero() && idle < threshold:
+		away := time.Since(m.idleSince) - idle // Up to when they touched the keyboard
+		m.idleSince = time.Time{}
+		m.report(IdleReport{Returned: true, Idle: away})
+	}
+}
+
+// shouldIdlePause reports whether going idle pauses the session
+func shouldIdlePause(state string, session *SessionSlot) bool {
+	return state == TimerRunning && session != nil && session.Type == SessionWork
+}
+
+// restoreIdleTime puts seconds away back on the clock without going past the session length
+func restoreIdleTime(remaining, duration, away int) int {
+	remaining += away
+	if remaining > duration {
+		remaining = duration
+	}
+	return remaining
+}
+
+// handleIdleReport pauses work sessions when the user wanders off (timer goroutine)
+func handleIdleReport(report IdleReport) {
+	if !report.Returned {
+		if !shouldIdlePause(currentState, sessionManager.GetCurrentSession()) {
+			return
+		}
+		pauseTimer()
+		idlePaused = true
+		idleAwaySeconds = int(report.Idle.Seconds())
+		idleSessionIndex = sessionManager.CurrentIndex
+		return
+	}
+
+	if !idlePaused {
+		return
+	}
+	idlePaused = false
+	if DefaultSettings.Idle.AskOnReturn {
+		away := idleAwaySeconds
+		fyne.Do(func() {
+			askAboutIdleTime(away)
+		})
+		return
+	}
+	if DefaultSettings.Idle.SubtractIdle {
+		discardIdleTime()
+	} else {
+		idleAwaySeconds = 0
+	}
+}
+
+// discardIdleTime puts the time away back on the auto-paused session (timer goroutine)
+func discardIdleTime() {
+	current := sessionManager.GetCurrentSession()
+	if idleAwaySeconds > 0 && current != nil && sessionManager.CurrentIndex == idleSessionIndex &&
+		(currentState == TimerPaused || currentState == TimerRunning) {
+		timeRemaining = restoreIdleTime(timeRemaining, current.Duration, idleAwaySeconds)
+		updateUI()
+	}
+	idleAwaySeconds = 0
+}
+
+// askAboutIdleTime asks whether the time away counts as focus, answering
+// through controlChannel (UI goroutine)
+func askAboutIdleTime(awaySeconds int) {
+	message := fmt.Sprintf("Ye were away from the helm for %s before the session was paused.\nCount that time as focus?",
+		formatWarningTime(awaySeconds))
+	dialog.ShowConfirm("Welcome back, matey!", message, func(keep bool) {
+		if keep {
+			controlChannel <- IdleKeep
+		} else {
+			controlChannel <- IdleDiscard
+		}
+	}, myWindow)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeIdleSource reports whatever idle time the test sets
+type fakeIdleSource struct {
+	idle time.Duration
+}
+
+func (f *fakeIdleSource) Name() string                     { return "fake" }
+func (f *fakeIdleSource) IdleTime() (time.Duration, error) { return f.idle, nil }
+
+func TestIdleMonitorReportsLeavingAndReturning(t *testing.T) {
+	source := &fakeIdleSource{}
+	var reports []IdleReport
+	monitor := NewIdleMonitor(source,
+		func() time.Duration { return 5 * time.Minute },
+		func(report IdleReport) { reports = append(reports, report) },
+	)
+
+	source.idle = 4 * time.Minute
+	monitor.check()
+	if len(reports) != 0 {
+		t.Fatalf("Below the threshold should not report, got %v", reports)
+	}
+
+	source.idle = 6 * time.Minute
+	monitor.check()
+	monitor.check() // Still idle - no second report
+	if len(reports) != 1 || reports[0].Returned || reports[0].Idle != 6*time.Minute {
+		t.Fatalf("Expected one idle report, got %v", reports)
+	}
+
+	source.idle = 2 * time.Second
+	monitor.check()
+	if len(reports) != 2 || !reports[1].Returned {
+		t.Fatalf("Expected a return report, got %v", reports)
+	}
+	if reports[1].Idle < 5*time.Minute {
+		t.Errorf("Time away should include the idle time before detection, got %v", reports[1].Idle)
+	}
+}
+
+func TestIdleMonitorDisabled(t *testing.T) {
+	source := &fakeIdleSource{idle: time.Hour}
+	monitor := NewIdleMonitor(source,
+		func() time.Duration { return 0 },
+		func(report IdleReport) { t.Errorf("Disabled monitor reported %v", report) },
+	)
+	monitor.check()
+}
+
+func TestShouldIdlePause(t *testing.T) {
+	work := &SessionSlot{Type: SessionWork}
+	rest := &SessionSlot{Type: SessionShortBreak}
+
+	if !shouldIdlePause(TimerRunning, work) {
+		t.Error("Running work sessions should pause when idle")
+	}
+	if shouldIdlePause(TimerRunning, rest) {
+		t.Error("Breaks should keep running while idle")
+	}
+	if shouldIdlePause(TimerPaused, work) || shouldIdlePause(TimerRunning, nil) {
+		t.Error("Only running sessions pause")
+	}
+}
+
+func TestRestoreIdleTime(t *testing.T) {
+	if got := restoreIdleTime(600, 1500, 300); got != 900 {
+		t.Errorf("Expected 900, got %d", got)
+	}
+	if got := restoreIdleTime(1400, 1500, 300); got != 1500 {
+		t.Errorf("Should not go past the session length, got %d", got)
+	}
+}
+
+// fakeScreenSaver answers GetSessionIdleTime like KDE's screensaver service
+type fakeScreenSaver struct {
+	seconds uint32
+}
+
+func (s fakeScreenSaver) GetSessionIdleTime() (uint32, *dbus.Error) {
+	return s.seconds, nil
+}
+
+func TestScreenSaverIdleSource(t *testing.T) {
+	address := startTestBus(t)
+	server := connectTestBus(t, address)
+	if err := server.Export(fakeScreenSaver{seconds: 90}, "/org/freedesktop/ScreenSaver", "org.freedesktop.ScreenSaver"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.RequestName("org.freedesktop.ScreenSaver", dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+
+	source := screenSaverIdleSource{connectTestBus(t, address)}
+	idle, err := source.IdleTime()
+	if err != nil {
+		t.Fatalf("IdleTime failed: %v", err)
+	}
+	if idle != 90*time.Second {
+		t.Errorf("Expected 90s, got %v", idle)
+	}
+}
+
+func TestIdleReturnAsksOnTheWindow(t *testing.T) {
+	useTestMiniWindow(t)
+	DefaultSettings.Idle.AskOnReturn = true
+	currentState = TimerRunning
+	t.Cleanup(func() { idlePaused, idleAwaySeconds = false, 0 })
+
+	handleIdleReport(IdleReport{Idle: 6 * time.Minute})
+	if currentState != TimerPaused {
+		t.Fatalf("Expected an idle pause, got %s", currentState)
+	}
+	handleIdleReport(IdleReport{Idle: 6 * time.Minute, Returned: true})
+	if myWindow.Canvas().Overlays().Top() == nil {
+		t.Error("Expected the welcome back question")
+	}
+	select {
+	case command := <-controlChannel:
+		t.Errorf("Nothing should be decided before the answer, got %q", command)
+	default:
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyImporters turn another app's export into history records
+var historyImporters = map[string]func(io.Reader, map[string]string) ([]HistoryRecord, error){
+	"csv":   importGenericCSV,
+	"toggl": importTogglCSV,
+	"json":  importJSONLog,
+}
+
+// defaultCSVColumns maps our fields to CSV column names (GoModoro's own CSV export)
+var defaultCSVColumns = map[string]string{
+	"id":       "id",
+	"kind":     "kind",
+	"type":     "session_type",
+	"task":     "task",
+	"start":    "start",
+	"end":      "end",
+	"duration": "duration",
+	"elapsed":  "elapsed",
+}
+
+// importTimeLayouts are the timestamp formats we recognise, tried in order
+var importTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+}
+
+// runImportCommand reads another app's export into the history log
+func runImportCommand(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "csv", "input format: "+importFormatNames())
+	columns := flags.String("map", "", "CSV column mapping, e.g. start=Started,end=Ended,task=Title")
+	dryRun := flags.Bool("dry-run", false, "show what would be imported without saving it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gomodoro import [--format csv|toggl|json] [--map ...] [--dry-run] FILE")
+		return 2
+	}
+
+	importer, ok := historyImporters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q (available: %s)\n", *format, importFormatNames())
+		return 2
+	}
+	mapping, err := parseColumnMapping(*columns)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro import:", err)
+		return 2
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro import:", err)
+		return 1
+	}
+	defer file.Close()
+
+	incoming, err := importer(file, mapping)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro import:", err)
+		return 1
+	}
+
+	history := openHistoryStore()
+	existing, err := history.Query(HistoryQuery{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro import:", err)
+		return 1
+	}
+	fresh, duplicates := dedupeImport(existing, incoming)
+
+	if *dryRun {
+		fmt.Printf("Would import %d sessions (%d duplicates skipped):\n", len(fresh), duplicates)
+		for _, record := range fresh {
+			fmt.Printf("  %s  %-11s %6s  %s\n", record.Start.Local().Format("2006-01-02 15:04"),
+				record.SessionType, formatReportDuration(record.Elapsed), record.Task)
+		}
+		return 0
+	}
+
+	if err := history.Append(fresh...); err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro import:", err)
+		return 1
+	}
+	fmt.Printf("Imported %d sessions (%d duplicates skipped)\n", len(fresh), duplicates)
+	return 0
+}
+
+// importFormatNames lists the formats for help and error messages
+func importFormatNames() string {
+	names := make([]string, 0, len(historyImporters))
+	for name := range historyImporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseColumnMapping reads field=Column pairs
+func parseColumnMapping(value string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if value == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || field == "" {
+			return nil, fmt.Errorf("bad column mapping %q (want field=Column)", pair)
+		}
+		if _, known := defaultCSVColumns[field]; !known {
+			return nil, fmt.Errorf("unknown field %q in column mapping", field)
+		}
+		mapping[field] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+// dedupeImport drops incoming records already in history (or repeated in the
+// import), matching on ID or on session type and start time
+func dedupeImport(existing, incoming []HistoryRecord) (fresh []HistoryRecord, duplicates int) {
+	seen := map[string]bool{}
+	for _, record := range existing {
+		seen[record.ID] = true
+		seen[importDedupeKey(record)] = true
+	}
+	for _, record := range incoming {
+		if seen[record.ID] || seen[importDedupeKey(record)] {
+			duplicates++
+			continue
+		}
+		seen[record.ID] = true
+		seen[importDedupeKey(record)] = true
+		fresh = append(fresh, record)
+	}
+	return fresh, duplicates
+}
+
+// importDedupeKey identifies a session by what it was and when it started
+func importDedupeKey(record HistoryRecord) string {
+	return string(record.SessionType) + "@" + strconv.FormatInt(record.Start.Unix(), 10)
+}
+
+// importedRecord fills in the gaps of an imported session
+func importedRecord(source string, start, end time.Time, task string, sessionType SessionType, kind HistoryKind) HistoryRecord {
+	if sessionType == "" {
+		sessionType = SessionWork
+	}
+	if kind == "" {
+		kind = HistoryCompleted
+	}
+	seconds := int(end.Sub(start).Seconds())
+	record := HistoryRecord{
+		Kind:        kind,
+		SessionType: sessionType,
+		Task:        task,
+		Start:       start,
+		End:         end,
+		Duration:    seconds,
+		Elapsed:     seconds,
+		Note:        "imported from " + source,
+	}
+	// Same session, same ID - re-importing the file is a no-op
+	sum := sha256.Sum256([]byte(source + "|" + start.UTC().Format(time.RFC3339) + "|" + end.UTC().Format(time.RFC3339) + "|" + task))
+	record.ID = "import-" + hex.EncodeToString(sum[:8])
+	return record
+}
+
+// parseImportTime tries each known layout, in local time unless the value says otherwise
+func parseImportTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", value)
+}
+
+// parseImportDuration reads seconds, HH:MM:SS, HH:MM or Go durations like 25m
+func parseImportDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if parts := strings.Split(value, ":"); len(parts) >= 2 && len(parts) <= 3 {
+		total := 0
+		for _, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("unrecognised duration %q", value)
+			}
+			total = total*60 + n
+		}
+		if len(parts) == 2 {
+			total *= 60 // HH:MM
+		}
+		return time.Duration(total) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// readCSVRows reads a CSV file into header-keyed rows
+func readCSVRows(r io.Reader) ([]map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Spreadsheet BOM
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := map[string]string{}
+		for i, name := range header {
+			if i < len(row) {
+				record[strings.TrimSpace(name)] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// importGenericCSV reads any CSV with a start and an end or duration column
+func importGenericCSV(r io.Reader, mapping map[string]string) ([]HistoryRecord, error) {
+	columns := map[string]string{}
+	for field, column := range defaultCSVColumns {
+		columns[field] = column
+	}
+	for field, column := range mapping {
+		columns[field] = column
+	}
+
+	rows, err := readCSVRows(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []HistoryRecord
+	for i, row := range rows {
+		line := i + 2 // 1-based, after the header
+		start, err := parseImportTime(row[columns["start"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: start: %v", line, err)
+		}
+
+		var end time.Time
+		hasEnd := row[columns["end"]] != ""
+		if hasEnd {
+			if end, err = parseImportTime(row[columns["end"]]); err != nil {
+				return nil, fmt.Errorf("line %d: end: %v", line, err)
+			}
+		} else {
+			duration, err := parseImportDuration(row[columns["duration"]])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: needs an end or a duration: %v", line, err)
+			}
+			end = start.Add(duration)
+		}
+
+		record := importedRecord("csv", start, end, row[columns["task"]],
+			SessionType(row[columns["type"]]), HistoryKind(row[columns["kind"]]))
+		if id := row[columns["id"]]; id != "" {
+			record.ID = id // Our own export - keep the original ID
+		}
+		// With an end as well, the duration is the planned length (as in our
+		// own export), and time paused doesn't count towards what was done
+		if value := row[columns["duration"]]; hasEnd && value != "" {
+			duration, err := parseImportDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: duration: %v", line, err)
+			}
+			record.Duration = int(duration.Seconds())
+		}
+		if value := row[columns["elapsed"]]; value != "" {
+			elapsed, err := parseImportDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: elapsed: %v", line, err)
+			}
+			record.Elapsed = int(elapsed.Seconds())
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// importTogglCSV reads Toggl Track's detailed CSV export
+func importTogglCSV(r io.Reader, _ map[string]string) ([]HistoryRecord, error) {
+	rows, err := readCSVRows(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []HistoryRecord
+	for i, row := range rows {
+		line := i + 2
+		start, err := parseImportTime(row["Start date"] + " " + row["Start time"])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: start: %v", line, err)
+		}
+		end, err := parseImportTime(row["End date"] + " " + row["End time"])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: end: %v", line, err)
+		}
+
+		task := row["Description"]
+		if project := row["Project"]; project != "" {
+			if task == "" {
+				task = project
+			} else {
+				task = project + ": " + task
+			}
+		}
+		records = append(records, importedRecord("toggl", start, end, task, SessionWork, HistoryCompleted))
+	}
+	return records, nil
+}
+
+// jsonLogEntry is one session in a simple JSON log
+type jsonLogEntry struct {
+	ID       string      `json:"id"`
+	Kind     HistoryKind `json:"kind"`
+	Type     SessionType `json:"type"`
+	Session  SessionType `json:"session_type"` // Our own history.jsonl
+	Task     string      `json:"task"`
+	Start    string      `json:"start"`
+	End      string      `json:"end"`
+	Duration int         `json:"duration"` // Seconds, when there's no end
+}
+
+// importJSONLog reads a JSON array or JSON lines of {start, end|duration, task, type}
+func importJSONLog(r io.Reader, _ map[string]string) ([]HistoryRecord, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []jsonLogEntry
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var entry jsonLogEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	var records []HistoryRecord
+	for i, entry := range entries {
+		start, err := parseImportTime(entry.Start)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: start: %v", i+1, err)
+		}
+		end := start.Add(time.Duration(entry.Duration) * time.Second)
+		if entry.End != "" {
+			if end, err = parseImportTime(entry.End); err != nil {
+				return nil, fmt.Errorf("entry %d: end: %v", i+1, err)
+			}
+		}
+		sessionType := entry.Type
+		if sessionType == "" {
+			sessionType = entry.Session
+		}
+		record := importedRecord("json", start, end, entry.Task, sessionType, entry.Kind)
+		if entry.ID != "" {
+			record.ID = entry.ID
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportGenericCSVWithMapping(t *testing.T) {
+	input := "\xef\xbb\xbfStarted,Minutes,Title\n" +
+		"2024-03-04 09:00,25m,Write docs\n" +
+		"2024-03-04 09:30,1500,Review\n"
+	mapping, err := parseColumnMapping("start=Started, duration=Minutes, task=Title")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := importGenericCSV(strings.NewReader(input), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	first := records[0]
+	if first.Task != "Write docs" || first.Elapsed != 1500 || first.SessionType != SessionWork || first.Kind != HistoryCompleted {
+		t.Errorf("Unexpected record %+v", first)
+	}
+	if !first.End.Equal(time.Date(2024, 3, 4, 9, 25, 0, 0, time.Local)) {
+		t.Errorf("Unexpected end %v", first.End)
+	}
+
+	if _, err := parseColumnMapping("colour=Red"); err == nil {
+		t.Error("Unknown fields should be rejected")
+	}
+}
+
+func TestImportOwnCSVExportKeepsIDs(t *testing.T) {
+	var buf bytes.Buffer
+	writeHistoryCSV(&buf, testHistory())
+
+	records, err := importGenericCSV(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, duplicates := dedupeImport(testHistory(), records)
+	if len(fresh) != 0 || duplicates != 5 {
+		t.Errorf("Re-importing our own export should find only duplicates, got %d new, %d dupes", len(fresh), duplicates)
+	}
+
+	// Time run and planned length come back as they were, not end minus start
+	for i, want := range testHistory() {
+		if got := records[i]; got.Elapsed != want.Elapsed || got.Duration != want.Duration {
+			t.Errorf("%s: got %ds of %ds, want %ds of %ds", want.ID, got.Elapsed, got.Duration, want.Elapsed, want.Duration)
+		}
+	}
+}
+
+func TestImportTogglCSV(t *testing.T) {
+	input := "User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags\n" +
+		"Ann,ann@example.com,,GoModoro,,Calendar import,No,2024-03-04,09:00:00,2024-03-04,09:25:00,00:25:00,\n" +
+		"Ann,ann@example.com,,,,Email,No,2024-03-04,10:00:00,2024-03-04,10:10:00,00:10:00,\n"
+
+	records, err := importTogglCSV(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Task != "GoModoro: Calendar import" || records[1].Task != "Email" {
+		t.Fatalf("Unexpected records %+v", records)
+	}
+	if records[0].Elapsed != 25*60 || records[0].Note != "imported from toggl" {
+		t.Errorf("Unexpected first record %+v", records[0])
+	}
+
+	// Same file twice gives the same IDs
+	again, _ := importTogglCSV(strings.NewReader(input), nil)
+	if again[0].ID != records[0].ID {
+		t.Error("Import IDs should be stable")
+	}
+}
+
+func TestImportJSONLog(t *testing.T) {
+	array := `[{"start": "2024-03-04T09:00:00Z", "duration": 1500, "task": "Deep work"},
+	           {"start": "2024-03-04T09:25:00Z", "end": "2024-03-04T09:30:00Z", "type": "short_break"}]`
+	records, err := importJSONLog(strings.NewReader(array), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Elapsed != 1500 || records[1].SessionType != SessionShortBreak {
+		t.Errorf("Unexpected records %+v", records)
+	}
+
+	// JSON lines, like our own history.jsonl
+	var buf bytes.Buffer
+	writeHistoryJSONLines(&buf, testHistory())
+	records, err = importJSONLog(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || records[0].ID != "a1" || records[1].SessionType != SessionShortBreak {
+		t.Errorf("History JSON lines didn't import: %+v", records)
+	}
+}
+
+func TestDedupeImport(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	existing := []HistoryRecord{{ID: "x", SessionType: SessionWork, Start: start}}
+	incoming := []HistoryRecord{
+		{ID: "y", SessionType: SessionWork, Start: start},                          // Same session, other app
+		{ID: "z", SessionType: SessionWork, Start: start.Add(time.Hour)},           // New
+		{ID: "z2", SessionType: SessionWork, Start: start.Add(time.Hour)},          // Repeated in the file
+		{ID: "w", SessionType: SessionShortBreak, Start: start.Add(2 * time.Hour)}, // New
+	}
+	fresh, duplicates := dedupeImport(existing, incoming)
+	if len(fresh) != 2 || duplicates != 2 || fresh[0].ID != "z" || fresh[1].ID != "w" {
+		t.Errorf("Unexpected dedupe result %+v (%d dupes)", fresh, duplicates)
+	}
+}
+
+func TestParseImportDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"1500":     25 * time.Minute,
+		"00:25:00": 25 * time.Minute,
+		"1:05":     65 * time.Minute,
+		"25m":      25 * time.Minute,
+	}
+	for input, want := range tests {
+		if got, err := parseImportDuration(input); err != nil || got != want {
+			t.Errorf("%q: expected %v, got %v %v", input, want, got, err)
+		}
+	}
+}
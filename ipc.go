@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// controlListener is the running instance's local socket (nil if not serving)
+var controlListener net.Listener
+
+// getControlSocketPath returns where the running instance of the active
+// profile listens for local clients
+func getControlSocketPath() string {
+	// Use XDG_RUNTIME_DIR or fallback to the temp directory
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = os.TempDir()
+	}
+	// The default profile keeps the name from before profiles
+	if activeProfile == defaultProfile {
+		return filepath.Join(runtimeDir, fmt.Sprintf("gomodoro-%d.sock", os.Getuid()))
+	}
+	return filepath.Join(runtimeDir, fmt.Sprintf("gomodoro-%d-%s.sock", os.Getuid(), activeProfile))
+}
+
+// startControlSocket lets other processes (status bars, scripts) talk to this instance
+func startControlSocket() error {
+	socketPath := getControlSocketPath()
+
+	// A leftover socket from a crashed instance would block Listen
+	if _, err := queryRunningInstance("ping"); err != nil {
+		os.Remove(socketPath)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	controlListener = listener
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return // Listener closed
+			}
+			go handleControlConn(conn)
+		}
+	}()
+	return nil
+}
+
+// stopControlSocket closes the socket and removes it from disk
+func stopControlSocket() {
+	if controlListener != nil {
+		controlListener.Close()
+		controlListener = nil
+	}
+}
+
+// handleControlConn answers a single request line from a local client
+func handleControlConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	switch strings.TrimSpace(line) {
+	case "ping":
+		fmt.Fprintln(conn, "pong")
+	case "status":
+		data, err := json.Marshal(latestStatus())
+		if err != nil {
+			return
+		}
+		conn.Write(append(data, '\n'))
+	}
+}
+
+// queryRunningInstance sends one request to the running instance and returns its reply
+func queryRunningInstance(request string) (string, error) {
+	conn, err := net.DialTimeout("unix", getControlSocketPath(), 500*time.Millisecond)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := fmt.Fprintln(conn, request); err != nil {
+		return "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(reply), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// LifecycleEvent names a session transition that integrations can react to
+type LifecycleEvent string
+
+const (
+	LifecycleSessionStart  LifecycleEvent = "session_start"
+	LifecycleSessionFinish LifecycleEvent = "session_finish"
+	LifecycleSessionPause  LifecycleEvent = "session_pause"
+	LifecycleSessionSkip   LifecycleEvent = "session_skip"
+	LifecycleCycleComplete LifecycleEvent = "cycle_complete"
+	LifecycleSessionChange LifecycleEvent = "session_change" // A new session became current (next/skip)
+)
+
+// SessionEvent describes a transition and the session it happened to
+type SessionEvent struct {
+	Event       LifecycleEvent `json:"event"`
+	Time        time.Time      `json:"time"`
+	SessionType SessionType    `json:"session_type,omitempty"`
+	SessionNum  int            `json:"session_num,omitempty"`
+	Duration    int            `json:"duration"`  // in seconds
+	Remaining   int            `json:"remaining"` // in seconds
+	Task        string         `json:"task,omitempty"`
+	CycleIndex  int            `json:"cycle_index"` // 1-based position in the cycle
+	CycleLength int            `json:"cycle_length"`
+	Resumed     bool           `json:"resumed,omitempty"` // Start after a pause
+}
+
+// Listeners are called on the timer goroutine, so they must hand off slow work
+var (
+	lifecycleListeners   []func(SessionEvent)
+	lifecycleListenersMu sync.Mutex
+)
+
+// onLifecycleEvent registers a listener for every session transition
+func onLifecycleEvent(listener func(SessionEvent)) {
+	lifecycleListenersMu.Lock()
+	defer lifecycleListenersMu.Unlock()
+	lifecycleListeners = append(lifecycleListeners, listener)
+}
+
+// newSessionEvent describes the current session for an event
+func newSessionEvent(event LifecycleEvent) SessionEvent {
+	sessionEvent := SessionEvent{
+		Event:     event,
+		Time:      time.Now(),
+		Remaining: timeRemaining,
+	}
+	if sessionManager == nil {
+		return sessionEvent
+	}
+
+	sessionEvent.CycleIndex = sessionManager.CurrentIndex + 1
+	sessionEvent.CycleLength = len(sessionManager.Sessions)
+	if current := sessionManager.GetCurrentSession(); current != nil {
+		sessionEvent.SessionType = current.Type
+		sessionEvent.SessionNum = current.SessionNum
+		sessionEvent.Duration = current.Duration
+		sessionEvent.Task = current.Task
+	} else {
+		sessionEvent.CycleIndex = len(sessionManager.Sessions) // Ran off the end of the cycle
+	}
+	return sessionEvent
+}
+
+// emitLifecycleEvent tells every listener about a transition of the current session
+func emitLifecycleEvent(event LifecycleEvent) {
+	publishSessionEvent(newSessionEvent(event))
+}
+
+// publishSessionEvent hands an already built event to the listeners
+func publishSessionEvent(sessionEvent SessionEvent) {
+	lifecycleListenersMu.Lock()
+	listeners := append([]func(SessionEvent){}, lifecycleListeners...)
+	lifecycleListenersMu.Unlock()
+
+	for _, listener := range listeners {
+		listener(sessionEvent)
+	}
+}
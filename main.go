@@ -3,6 +3,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,18 +11,24 @@ import (
 	"fyne.io/fyne/v2"           // Base fyne package for types like Size
 	"fyne.io/fyne/v2/app"       // Creates the application
 	"fyne.io/fyne/v2/container" // Layout containers (VBox, HBox, etc)
+	"fyne.io/fyne/v2/dialog"    // Pop-up dialogs
 	"fyne.io/fyne/v2/widget"    // UI widgets (buttons, labels, etc)
 )
 
 // createTimerUI builds the main timer interface
 func createTimerUI() *fyne.Container {
-	// Initialize session manager
-	sessionManager = NewSessionManager()
+	// Initialize session manager (unless one was restored from disk)
+	if sessionManager == nil {
+		sessionManager = NewSessionManager()
+	}
 
 	// Create UI elements
 	title := widget.NewLabel("🍅 GoModoro Timer")
 	title.Alignment = fyne.TextAlignCenter
 
+	// Which profile's settings and history we're using
+	profileSelect := createProfileSelect()
+
 	// Current session label
 	currentSessionLabel = widget.NewLabel("")
 	currentSessionLabel.Alignment = fyne.TextAlignCenter
@@ -41,6 +48,14 @@ func createTimerUI() *fyne.Container {
 	remainingLabel = widget.NewLabel("Upcoming:")
 	remainingList = widget.NewLabel("")
 
+	// What we're working on - stamped onto work sessions as they start
+	taskEntry = widget.NewEntry()
+	taskEntry.SetPlaceHolder("What be ye working on?")
+	taskEntry.SetText(currentTask)
+	taskEntry.OnChanged = func(text string) {
+		currentTask = text
+	}
+
 	// Start/Pause button
 	startPauseBtn = widget.NewButton("🏴‍☠️ Start Timer!", func() {
 		switch currentState {
@@ -68,9 +83,24 @@ func createTimerUI() *fyne.Container {
 		showSettingsWindow()
 	})
 
+	// Stats button - focus totals, with reports and calendar exports from there
+	exportBtn := widget.NewButton("📊", func() {
+		showStatsWindow()
+	})
+
+	// Rooms button - host or join a team focus room
+	roomsBtn := widget.NewButton("👥", func() {
+		showRoomsDialog()
+	})
+
+	// Mini button - shrink to a small always-on-top countdown
+	miniBtn := widget.NewButton("🔽", func() {
+		showMiniWindow()
+	})
+
 	// Layout buttons more compactly
 	mainButtonContainer := container.NewHBox(startPauseBtn)
-	secondaryButtonContainer := container.NewHBox(resetBtn, skipBtn, settingsBtn)
+	secondaryButtonContainer := container.NewHBox(resetBtn, skipBtn, settingsBtn, exportBtn, roomsBtn, miniBtn)
 
 	// Compact session lists
 	sessionProgress := container.NewVBox(
@@ -84,12 +114,15 @@ func createTimerUI() *fyne.Container {
 	// Main layout - more compact
 	content := container.NewVBox(
 		title,
+		profileSelect,
 		currentSessionLabel,
-		widget.NewLabel(""), // Small spacer
+		taskEntry,
 		timeDisplay,
 		widget.NewLabel(""), // Small spacer
 		mainButtonContainer,
 		secondaryButtonContainer,
+		createRoomPanel(),
+		createTeamPanel(),
 		widget.NewLabel(""), // Small spacer
 		sessionProgress,
 	)
@@ -100,7 +133,32 @@ func createTimerUI() *fyne.Container {
 	return content
 }
 
+// quitApp saves state, stops the goroutines and exits
+func quitApp() {
+	saveAppState()
+	if globalHotkeys != nil {
+		globalHotkeys.Close() // Give the keys back
+	}
+	stopChannel <- true
+	myApp.Quit()
+}
+
 func main() {
+	// Pick the profile (--profile NAME, or the last one used) before touching any files
+	args, err := selectStartupProfile(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro:", err)
+		os.Exit(2)
+	}
+
+	// Command-line modes (e.g. `gomodoro status`) run without the GUI
+	if len(args) > 0 {
+		if code, handled := runSubcommand(args); handled {
+			os.Exit(code)
+		}
+	}
+	rememberProfile(activeProfile)
+
 	// Create the app - store in global variable
 	myApp = app.New()
 	myApp.SetIcon(nil)
@@ -117,11 +175,15 @@ func main() {
 
 	// Create the main window - store in global variable for notifications
 	myWindow = myApp.NewWindow("GoModoro - Pomodoro Timer")
+	if activeProfile != defaultProfile {
+		myWindow.SetTitle("GoModoro - Pomodoro Timer (" + activeProfile + ")")
+	}
 	myWindow.Resize(fyne.NewSize(400, 500)) // Smaller height
 
 	// Create and set the main UI
 	content := createTimerUI()
 	myWindow.SetContent(content)
+	setupWindowShortcuts(myWindow.Canvas())
 	myWindow.CenterOnScreen()
 
 	// Set up signal handling for graceful shutdown
@@ -131,25 +193,86 @@ func main() {
 	go func() {
 		<-sigChan
 		// Save state before exiting
-		saveAppState()
-		stopChannel <- true
-		myApp.Quit()
+		quitApp()
 	}()
 
-	// Start the timer goroutine BEFORE showing the window
+	// Start the timer goroutine BEFORE showing the window (it publishes the
+	// status from here on)
+	publishStatus()
 	go timerGoroutine()
 
 	// Start auto-save goroutine
 	go autoSaveState()
 
-	// Handle window closing - send stop signal to goroutine
+	// Serve status to `gomodoro status` and friends (not fatal if it fails)
+	if err := startControlSocket(); err == nil {
+		defer stopControlSocket()
+	}
+
+	// Desktop notifications over D-Bus (logs and carries on without a bus)
+	setupDesktopNotifications()
+
+	// Pick a sound player (sounds are silently off without one)
+	setupAudio()
+
+	// Log finished and skipped sessions to the history database
+	setupHistory()
+
+	// User shell hooks on session start/finish/pause/skip
+	setupHooks()
+
+	// Outbound webhooks (queued on disk while offline)
+	setupWebhooks()
+
+	// MPRIS media player control between work and breaks
+	setupMediaControl()
+
+	// Auto-pause work sessions when nobody's at the keyboard
+	setupIdleDetection()
+
+	// Pause or keep going when the screen locks or the machine sleeps
+	setupPowerWatch()
+
+	// Meetings from ICS calendars (warn, shorten or skip work sessions)
+	setupCalendar()
+	setupCalendarExport()
+
+	// Share history, settings and the cycle through a synced folder
+	setupSync()
+
+	// Team focus rooms over the LAN
+	setupRooms()
+
+	// Let teammates see whether we're mid-pomodoro (and see theirs)
+	setupPresence()
+
+	// System-wide shortcuts, if any are set (X11 or the GlobalShortcuts portal)
+	setupGlobalHotkeys()
+
+	// Tray icon with countdown and controls (desktop only)
+	setupSystemTray()
+
+	// Handle window closing - hide to the tray if asked, otherwise quit
 	myWindow.SetCloseIntercept(func() {
-		// Save state on window close
-		saveAppState()
-		stopChannel <- true
-		myApp.Quit()
+		if DefaultSettings.MinimizeToTray && trayApp != nil {
+			myWindow.Hide()
+			return
+		}
+		quitApp()
 	})
 
+	// Tell the user if their state had to be recovered
+	if stateNotice != "" {
+		dialog.ShowInformation("🩹 State recovered", stateNotice, myWindow)
+	}
+
+	// Start as the mini window if that's how we were left (unless there's news)
+	if windowState.Mini && stateNotice == "" {
+		showMiniWindow()
+		myApp.Run()
+		return
+	}
+
 	// Show the window and run (this blocks until window closes)
 	myWindow.ShowAndRun()
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// stateSchemaVersion is the session_state.json layout this build writes.
+// Bump it and add a step to stateMigrations whenever a saved field is
+// renamed, moved or changes meaning.
+const stateSchemaVersion = 2
+
+// errStateTooNew means the state file was written by a newer GoModoro
+var errStateTooNew = errors.New("state file is from a newer GoModoro")
+
+// stateMigrations upgrade a state file from the version they're keyed by to
+// the next one. They work on the raw JSON so fields the current structs no
+// longer have can still be read and moved.
+var stateMigrations = map[int]func(state map[string]json.RawMessage) error{
+	1: migrateStateV1,
+}
+
+// migrateState upgrades saved state step by step to stateSchemaVersion.
+// Files from before versioning have no schema_version and count as version 1.
+func migrateState(data []byte) ([]byte, error) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	version := 1
+	if raw, ok := state["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("bad schema_version: %w", err)
+		}
+	}
+	if version > stateSchemaVersion {
+		return nil, fmt.Errorf("%w (version %d, this build knows up to %d)", errStateTooNew, version, stateSchemaVersion)
+	}
+	if version == stateSchemaVersion {
+		return data, nil
+	}
+
+	for ; version < stateSchemaVersion; version++ {
+		migrate, ok := stateMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from state version %d", version)
+		}
+		if err := migrate(state); err != nil {
+			return nil, fmt.Errorf("migrating state from version %d: %w", version, err)
+		}
+	}
+	state["schema_version"], _ = json.Marshal(stateSchemaVersion)
+	return json.Marshal(state)
+}
+
+// migrateStateV1 drops zeroed cycle settings. Until the defaults were used as
+// the starting point for loading, a key missing from a hand-edited file was
+// read as 0 and saved back that way, leaving a cycle with no sessions or
+// zero-minute breaks. Dropping them lets the defaults fill in.
+func migrateStateV1(state map[string]json.RawMessage) error {
+	raw, ok := state["settings"]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return err
+	}
+	// Surprises and LongBreakFrequency can legitimately be 0 (none)
+	for _, key := range []string{"Sessions", "ShortBreak", "LongBreak", "SurpriseMinutes"} {
+		var value int
+		if err := json.Unmarshal(settings[key], &value); err == nil && value <= 0 {
+			delete(settings, key)
+		}
+	}
+	var err error
+	state["settings"], err = json.Marshal(settings)
+	return err
+}
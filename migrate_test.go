@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readStateFixture loads a saved state file from each historical layout
+func readStateFixture(t *testing.T, name string) *AppState {
+	t.Helper()
+	state, err := readAppState(filepath.Join("testdata", "state", name))
+	if err != nil || state == nil {
+		t.Fatalf("Reading %s: %v", name, err)
+	}
+	return state
+}
+
+func TestMigrateBaselineState(t *testing.T) {
+	state := readStateFixture(t, "v1-baseline.json")
+
+	if state.Settings.Sessions != 2 || state.Settings.LongBreak != 30 {
+		t.Errorf("Saved settings should survive, got %+v", state.Settings)
+	}
+	// Zeroed by the old loader, so back to the defaults...
+	if state.Settings.ShortBreak != DefaultSettings.ShortBreak || state.Settings.SurpriseMinutes != DefaultSettings.SurpriseMinutes {
+		t.Errorf("Zeroed settings should fall back to defaults, got %+v", state.Settings)
+	}
+	// ...but zero surprises and no long breaks are real choices
+	if state.Settings.Surprises != 0 || state.Settings.LongBreakFrequency != 0 {
+		t.Errorf("Zero surprises/long breaks should be kept, got %+v", state.Settings)
+	}
+	if state.Settings.Sounds.Finish.File != DefaultSoundSettings.Finish.File {
+		t.Error("Settings newer than the file should keep their defaults")
+	}
+	if state.SessionManagerState.CurrentIndex != 1 || len(state.SessionManagerState.Sessions) != 3 {
+		t.Errorf("Session list should survive, got %+v", state.SessionManagerState)
+	}
+	if state.TimeRemaining != 120 || state.SchemaVersion != stateSchemaVersion {
+		t.Errorf("Got time %d, version %d", state.TimeRemaining, state.SchemaVersion)
+	}
+}
+
+func TestMigrateUnversionedTaskState(t *testing.T) {
+	state := readStateFixture(t, "v1-tasks.json")
+
+	current := state.SessionManagerState.GetCurrentSession()
+	if current == nil || current.Task != "Chart the reef" || len(current.WarningsFired) != 1 {
+		t.Errorf("Current session lost its details: %+v", current)
+	}
+	if state.SessionManagerState.Sessions[1].Meeting != "Crew standup" || state.CurrentTask != "Chart the reef" {
+		t.Errorf("Meeting and task should survive, got %+v", state)
+	}
+	if !state.Settings.MinimizeToTray || len(state.Settings.Hooks) != 1 || state.Settings.WarningMinutes[SessionWork][0] != 5 {
+		t.Errorf("Settings should survive, got %+v", state.Settings)
+	}
+}
+
+func TestReadCurrentState(t *testing.T) {
+	state := readStateFixture(t, "v2.json")
+	if state.SchemaVersion != 2 || state.Settings.Sessions != 1 || state.CurrentTask != "Mend the sails" {
+		t.Errorf("Got %+v", state)
+	}
+}
+
+func TestMigrateStateIsIdempotent(t *testing.T) {
+	data, _ := os.ReadFile(filepath.Join("testdata", "state", "v1-baseline.json"))
+	once, err := migrateState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice, err := migrateState(once)
+	if err != nil || string(once) != string(twice) {
+		t.Errorf("Migrating a current file should leave it alone: %v", err)
+	}
+}
+
+func TestNewerStateIsLeftAlone(t *testing.T) {
+	dir := useTestConfigDir(t)
+	os.MkdirAll(dir, 0755)
+	statePath := getStateFilePath()
+	newer := `{"schema_version": 99, "current_state": "ready", "brand_new_field": {"keep": "me"}}`
+	os.WriteFile(statePath, []byte(newer), 0644)
+	os.WriteFile(stateBackupPath(statePath, 1), []byte(`{"current_state": "ready"}`), 0644)
+	t.Cleanup(func() { stateFrozen = false })
+
+	err := loadAppState()
+	if !errors.Is(err, errStateTooNew) {
+		t.Fatalf("Expected errStateTooNew, got %v", err)
+	}
+	if !strings.Contains(stateNotice, "newer GoModoro") {
+		t.Errorf("The user should be told, got %q", stateNotice)
+	}
+	if err := saveAppState(); !errors.Is(err, errStateTooNew) {
+		t.Errorf("Saving over a newer file should be refused, got %v", err)
+	}
+	if data, _ := os.ReadFile(statePath); string(data) != newer {
+		t.Errorf("Newer state file was changed: %s", data)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// WindowState is how the windows were left on this device (not synced)
+type WindowState struct {
+	Mini       bool `json:"mini"`                  // Showing the mini window instead of the full one
+	MiniX      int  `json:"mini_x,omitempty"`      // Where the mini window was dragged to,
+	MiniY      int  `json:"mini_y,omitempty"`      // in screen pixels
+	MiniPlaced bool `json:"mini_placed,omitempty"` // MiniX/MiniY are set (otherwise it starts centred)
+}
+
+// miniWindowSize fits the icon and countdown with a little room
+var miniWindowSize = fyne.NewSize(150, 36)
+
+// Mini window state
+var (
+	windowState   WindowState
+	miniWindow    fyne.Window
+	miniTimerView *miniTimer
+)
+
+// miniTimer is the mini window's only content: a progress fill behind the
+// session icon and countdown. Click to start/pause, double-click for the
+// full window, drag to move it (X11).
+type miniTimer struct {
+	widget.BaseWidget
+	text     string
+	progress float32 // 0 to 1 through the current session
+	color    string  // Session colour as statusColor gives it
+
+	dragging   bool
+	dragOffset [2]int // Pointer position within the window when the drag started
+}
+
+// newMiniTimer creates the mini countdown widget
+func newMiniTimer() *miniTimer {
+	m := &miniTimer{}
+	m.ExtendBaseWidget(m)
+	return m
+}
+
+// show puts a status on the widget
+func (m *miniTimer) show(status StatusSnapshot) {
+	m.text = statusText(status)
+	m.color = statusColor(status)
+	m.progress = 0
+	if status.Duration > 0 {
+		m.progress = float32(status.Duration-status.TimeRemaining) / float32(status.Duration)
+	}
+	if status.State == TimerFinished {
+		m.progress = 1
+	}
+	m.Refresh()
+}
+
+// Tapped toggles start/pause, like the main button
+func (m *miniTimer) Tapped(*fyne.PointEvent) {
+	runHotkey("start_pause")
+}
+
+// DoubleTapped goes back to the full window
+func (m *miniTimer) DoubleTapped(*fyne.PointEvent) {
+	showFullWindow()
+}
+
+// Dragged moves the (borderless) window with the pointer
+func (m *miniTimer) Dragged(*fyne.DragEvent) {
+	conn, id := miniX11()
+	if conn == nil {
+		return // The compositor places windows; nothing to do
+	}
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+	pointer, err := xproto.QueryPointer(conn, root).Reply()
+	if err != nil {
+		return
+	}
+	if !m.dragging {
+		x, y, err := x11WindowPosition(conn, id)
+		if err != nil {
+			return
+		}
+		m.dragging = true
+		m.dragOffset = [2]int{int(pointer.RootX) - x, int(pointer.RootY) - y}
+	}
+	moveX11Window(conn, id, int(pointer.RootX)-m.dragOffset[0], int(pointer.RootY)-m.dragOffset[1])
+}
+
+// DragEnd remembers where the window was left
+func (m *miniTimer) DragEnd() {
+	if !m.dragging {
+		return
+	}
+	m.dragging = false
+	rememberMiniPosition()
+	saveAppState()
+}
+
+// CreateRenderer draws the fill under the text
+func (m *miniTimer) CreateRenderer() fyne.WidgetRenderer {
+	r := &miniTimerRenderer{
+		timer: m,
+		back:  canvas.NewRectangle(theme.Color(theme.ColorNameBackground)),
+		fill:  canvas.NewRectangle(theme.Color(theme.ColorNamePrimary)),
+		text:  canvas.NewText("", theme.Color(theme.ColorNameForeground)),
+	}
+	r.text.TextStyle = fyne.TextStyle{Bold: true}
+	r.text.TextSize = theme.TextSize() * 1.3
+	r.text.Alignment = fyne.TextAlignCenter
+	r.Refresh()
+	return r
+}
+
+// miniTimerRenderer lays out the mini countdown
+type miniTimerRenderer struct {
+	timer *miniTimer
+	back  *canvas.Rectangle
+	fill  *canvas.Rectangle
+	text  *canvas.Text
+}
+
+func (r *miniTimerRenderer) Layout(size fyne.Size) {
+	r.back.Resize(size)
+	r.fill.Resize(fyne.NewSize(size.Width*r.timer.progress, size.Height))
+	textSize := r.text.MinSize()
+	r.text.Move(fyne.NewPos(0, (size.Height-textSize.Height)/2))
+	r.text.Resize(fyne.NewSize(size.Width, textSize.Height))
+}
+
+func (r *miniTimerRenderer) MinSize() fyne.Size {
+	return r.text.MinSize().Add(fyne.NewSize(theme.Padding()*4, theme.Padding()*2))
+}
+
+func (r *miniTimerRenderer) Refresh() {
+	r.back.FillColor = theme.Color(theme.ColorNameBackground)
+	fill := theme.Color(theme.ColorNamePrimary)
+	if r.timer.color != "" {
+		session := parseHexColor(r.timer.color)
+		session.A = 0x99 // Keep the text readable on top
+		fill = session
+	}
+	r.fill.FillColor = fill
+	r.text.Text = r.timer.text
+	r.text.Color = theme.Color(theme.ColorNameForeground)
+	r.Layout(r.timer.Size())
+	canvas.Refresh(r.timer)
+}
+
+func (r *miniTimerRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.back, r.fill, r.text}
+}
+
+func (r *miniTimerRenderer) Destroy() {}
+
+// showMiniWindow swaps the full window for the mini one
+func showMiniWindow() {
+	if fyne.CurrentDevice().IsMobile() {
+		return // One window is all there is
+	}
+	if miniWindow == nil {
+		if desk, ok := myApp.Driver().(desktop.Driver); ok {
+			miniWindow = desk.CreateSplashWindow() // Borderless
+		} else {
+			miniWindow = myApp.NewWindow("GoModoro")
+		}
+		miniWindow.SetTitle("GoModoro")
+		miniTimerView = newMiniTimer()
+		miniWindow.SetContent(miniTimerView)
+		miniWindow.Resize(miniWindowSize)
+		miniWindow.SetFixedSize(true)
+		miniWindow.SetCloseIntercept(showFullWindow)
+		setupWindowShortcuts(miniWindow.Canvas())
+	}
+	windowState.Mini = true
+	updateMiniWindow()
+	myWindow.Hide()
+	miniWindow.Show()
+	placeMiniWindow()
+	saveAppState()
+}
+
+// showFullWindow brings the full window up, leaving mini mode if in it
+func showFullWindow() {
+	wasMini := windowState.Mini
+	if miniWindow != nil && wasMini {
+		rememberMiniPosition()
+		miniWindow.Hide()
+	}
+	windowState.Mini = false
+	myWindow.Show()
+	myWindow.RequestFocus()
+	if wasMini {
+		saveAppState()
+	}
+}
+
+// toggleMiniWindow switches between the full and mini windows
+func toggleMiniWindow() {
+	if windowState.Mini {
+		showFullWindow()
+	} else {
+		showMiniWindow()
+	}
+}
+
+// updateMiniWindow refreshes the mini countdown from the timer state
+func updateMiniWindow() {
+	if miniTimerView == nil {
+		return
+	}
+	miniTimerView.show(currentStatus())
+}
+
+// Window placement and keep-on-top. Fyne can't do either, so on X11 we ask
+// the window manager ourselves; Wayland compositors decide for themselves.
+var (
+	miniX11Once sync.Once
+	miniX11Conn *xgb.Conn
+)
+
+// miniX11 returns an X connection and the mini window's id, or nil off X11
+func miniX11() (*xgb.Conn, xproto.Window) {
+	if miniWindow == nil {
+		return nil, 0
+	}
+	var id xproto.Window
+	if native, ok := miniWindow.(driver.NativeWindow); ok {
+		native.RunNative(func(context any) {
+			if x11, ok := context.(driver.X11WindowContext); ok {
+				id = xproto.Window(x11.WindowHandle)
+			}
+		})
+	}
+	if id == 0 {
+		return nil, 0
+	}
+	miniX11Once.Do(func() {
+		conn, err := xgb.NewConn()
+		if err != nil {
+			log.Printf("mini window: can't place the window: %v", err)
+			return
+		}
+		miniX11Conn = conn
+	})
+	if miniX11Conn == nil {
+		return nil, 0
+	}
+	return miniX11Conn, id
+}
+
+// placeMiniWindow keeps the mini window above the others and puts it back
+// where it was left
+func placeMiniWindow() {
+	conn, id := miniX11()
+	if conn == nil {
+		return
+	}
+	if windowState.MiniPlaced {
+		moveX11Window(conn, id, windowState.MiniX, windowState.MiniY)
+	}
+	if err := keepX11WindowAbove(conn, id); err != nil {
+		log.Printf("mini window: can't keep it on top: %v", err)
+	}
+}
+
+// rememberMiniPosition notes where the mini window is now
+func rememberMiniPosition() {
+	conn, id := miniX11()
+	if conn == nil {
+		return
+	}
+	if x, y, err := x11WindowPosition(conn, id); err == nil {
+		windowState.MiniX, windowState.MiniY, windowState.MiniPlaced = x, y, true
+	}
+}
+
+// x11WindowPosition is a window's top-left corner on the screen
+func x11WindowPosition(conn *xgb.Conn, id xproto.Window) (int, int, error) {
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+	position, err := xproto.TranslateCoordinates(conn, id, root, 0, 0).Reply()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(position.DstX), int(position.DstY), nil
+}
+
+// moveX11Window asks for a window to be moved
+func moveX11Window(conn *xgb.Conn, id xproto.Window, x, y int) {
+	xproto.ConfigureWindow(conn, id, xproto.ConfigWindowX|xproto.ConfigWindowY,
+		[]uint32{uint32(int32(x)), uint32(int32(y))})
+}
+
+// keepX11WindowAbove sets _NET_WM_STATE_ABOVE, both on the window (for when
+// it's mapped later) and by asking the window manager (for now)
+func keepX11WindowAbove(conn *xgb.Conn, id xproto.Window) error {
+	wmState, err := internX11Atom(conn, "_NET_WM_STATE")
+	if err != nil {
+		return err
+	}
+	above, err := internX11Atom(conn, "_NET_WM_STATE_ABOVE")
+	if err != nil {
+		return err
+	}
+
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, uint32(above))
+	xproto.ChangeProperty(conn, xproto.PropModeAppend, id, wmState, xproto.AtomAtom, 32, 1, value)
+
+	const netWMStateAdd = 1
+	event := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: id,
+		Type:   wmState,
+		Data:   xproto.ClientMessageDataUnionData32New([]uint32{netWMStateAdd, uint32(above), 0, 1, 0}),
+	}
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+	return xproto.SendEventChecked(conn, false, root,
+		xproto.EventMaskSubstructureRedirect|xproto.EventMaskSubstructureNotify, string(event.Bytes())).Check()
+}
+
+// internX11Atom looks up an atom by name
+func internX11Atom(conn *xgb.Conn, name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+	return reply.Atom, nil
+}
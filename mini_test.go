@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+)
+
+// useTestMiniWindow sets up the timer UI with a main window to swap with
+func useTestMiniWindow(t *testing.T) {
+	t.Helper()
+	useTestConfigDir(t)
+	sessionManager = newTestSessionManager(t, 2)
+	currentState = TimerReady
+	timeRemaining = sessionManager.GetCurrentSession().Duration
+	useTestUI(t)
+	myWindow = test.NewWindow(nil)
+	t.Cleanup(func() {
+		myWindow.Close()
+		if miniWindow != nil {
+			miniWindow.Close()
+		}
+		myWindow, miniWindow, miniTimerView = nil, nil, nil
+		windowState = WindowState{}
+	})
+}
+
+// savedWindowState reads the window state back from disk
+func savedWindowState(t *testing.T) WindowState {
+	t.Helper()
+	state, err := readAppState(getStateFilePath())
+	if err != nil || state == nil || state.Window == nil {
+		t.Fatalf("No window state saved: %v", err)
+	}
+	return *state.Window
+}
+
+func TestMiniWindowMode(t *testing.T) {
+	useTestMiniWindow(t)
+
+	showMiniWindow()
+	if !windowState.Mini || miniTimerView == nil {
+		t.Fatal("Expected the mini window")
+	}
+	if !savedWindowState(t).Mini {
+		t.Error("Mini mode should be saved")
+	}
+	if want := statusText(currentStatus()); miniTimerView.text != want {
+		t.Errorf("Expected %q, got %q", want, miniTimerView.text)
+	}
+
+	// A click starts or pauses, like the main button
+	test.Tap(miniTimerView)
+	if command := nextCommand(t); command != hotkeyCommandPrefix+"start_pause" {
+		t.Errorf("Click should start/pause, got %q", command)
+	}
+
+	// A double-click brings the full window back
+	test.DoubleTap(miniTimerView)
+	if windowState.Mini || savedWindowState(t).Mini {
+		t.Error("Double-click should leave mini mode")
+	}
+
+	// The hotkey swaps windows itself rather than asking the timer goroutine
+	runHotkey("mini")
+	if !windowState.Mini {
+		t.Error("The mini hotkey should switch to the mini window")
+	}
+	runHotkey("mini")
+	if windowState.Mini {
+		t.Error("The mini hotkey should switch back")
+	}
+	select {
+	case command := <-controlChannel:
+		t.Errorf("The mini hotkey sent %q", command)
+	default:
+	}
+
+	// And the mode comes back on the next start
+	windowState = WindowState{Mini: true, MiniX: 10, MiniY: 20, MiniPlaced: true}
+	saveAppState()
+	windowState = WindowState{}
+	if err := loadAppState(); err != nil {
+		t.Fatal(err)
+	}
+	if windowState != (WindowState{Mini: true, MiniX: 10, MiniY: 20, MiniPlaced: true}) {
+		t.Errorf("Window state not restored: %+v", windowState)
+	}
+}
+
+func TestMiniTimerProgress(t *testing.T) {
+	useTestMiniWindow(t)
+	showMiniWindow()
+	miniTimerView.Resize(fyne.NewSize(100, 30))
+	renderer := test.WidgetRenderer(miniTimerView).(*miniTimerRenderer)
+
+	duration := sessionManager.GetCurrentSession().Duration
+	currentState, timeRemaining = TimerRunning, duration/4
+	updateUI()
+	if width := renderer.fill.Size().Width; width != 75 {
+		t.Errorf("Three quarters through should fill 75, got %v", width)
+	}
+	if renderer.text.Text != "🍅 "+formatTime(duration/4) {
+		t.Errorf("Unexpected text %q", renderer.text.Text)
+	}
+
+	currentState = TimerPaused
+	updateUI()
+	if renderer.text.Text != "🍅 "+formatTime(duration/4)+" ⏸" {
+		t.Errorf("Paused should show, got %q", renderer.text.Text)
+	}
+}
+
+func TestSyncSnapshotLeavesWindowOut(t *testing.T) {
+	useTestConfigDir(t)
+	sessionManager = newTestSessionManager(t, 2)
+	windowState = WindowState{Mini: true}
+	defer func() { windowState = WindowState{} }()
+	if ownSnapshot().State.Window != nil {
+		t.Error("Window state is per device and shouldn't sync")
+	}
+}
+
+func TestFocusAlertLeavesMiniMode(t *testing.T) {
+	useTestMiniWindow(t)
+	showMiniWindow()
+	if err := (focusAlertNotifier{}).Notify(Alert{Event: EventSessionFinished}); err != nil {
+		t.Fatal(err)
+	}
+	if windowState.Mini {
+		t.Error("The focus alert should bring the full window back, not leave the mini one up")
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// MPRIS D-Bus names
+const (
+	mprisBusPrefix       = "org.mpris.MediaPlayer2."
+	mprisPath            = "/org/mpris/MediaPlayer2"
+	mprisPlayerInterface = "org.mpris.MediaPlayer2.Player"
+)
+
+// MediaAction is what to do with media players when a session comes up
+type MediaAction string
+
+const (
+	MediaPause  MediaAction = "pause"  // Pause everything that's playing
+	MediaResume MediaAction = "resume" // Play whatever we paused earlier
+)
+
+// MediaSettings configures MPRIS media player control
+type MediaSettings struct {
+	Enabled   bool                        `json:"enabled"`
+	OnSession map[SessionType]MediaAction `json:"on_session"`
+}
+
+// DefaultMediaSettings - off, but set up to pause music for breaks once enabled
+var DefaultMediaSettings = MediaSettings{
+	Enabled: false,
+	OnSession: map[SessionType]MediaAction{
+		SessionShortBreak: MediaPause,
+		SessionLongBreak:  MediaPause,
+		SessionWork:       MediaResume,
+	},
+}
+
+// MediaController pauses and resumes MPRIS players on its own goroutine
+type MediaController struct {
+	conn    *dbus.Conn
+	actions chan MediaAction
+
+	mu         sync.Mutex
+	pausedByUs []string // Bus names of players we paused
+}
+
+// Global media controller (nil if there's no session bus)
+var mediaController *MediaController
+
+// NewMediaController starts a worker that applies media actions in order
+func NewMediaController(conn *dbus.Conn) *MediaController {
+	m := &MediaController{
+		conn:    conn,
+		actions: make(chan MediaAction, 8),
+	}
+	go m.run()
+	return m
+}
+
+// setupMediaControl connects to the session bus and follows session changes
+func setupMediaControl() {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		log.Printf("media: no session bus, media control disabled: %v", err)
+		return
+	}
+	mediaController = NewMediaController(conn)
+	onLifecycleEvent(func(sessionEvent SessionEvent) {
+		if sessionEvent.Event != LifecycleSessionChange || !DefaultSettings.Media.Enabled {
+			return
+		}
+		if action, ok := DefaultSettings.Media.OnSession[sessionEvent.SessionType]; ok {
+			mediaController.Apply(action)
+		}
+	})
+}
+
+// Apply queues an action without waiting for D-Bus
+func (m *MediaController) Apply(action MediaAction) {
+	select {
+	case m.actions <- action:
+	default:
+		log.Printf("media: dropping %s, controller is busy", action)
+	}
+}
+
+// Close stops the worker
+func (m *MediaController) Close() {
+	close(m.actions)
+}
+
+// run applies queued actions one at a time
+func (m *MediaController) run() {
+	for action := range m.actions {
+		var err error
+		switch action {
+		case MediaPause:
+			err = m.PauseAll()
+		case MediaResume:
+			err = m.ResumePaused()
+		default:
+			log.Printf("media: unknown action %q", action)
+		}
+		if err != nil {
+			log.Printf("media: %s failed: %v", action, err)
+		}
+	}
+}
+
+// players lists the bus names of every MPRIS player
+func (m *MediaController) players() ([]string, error) {
+	var names []string
+	if err := m.conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return nil, err
+	}
+	players := make([]string, 0)
+	for _, name := range names {
+		if strings.HasPrefix(name, mprisBusPrefix) {
+			players = append(players, name)
+		}
+	}
+	return players, nil
+}
+
+// playbackStatus returns "Playing", "Paused" or "Stopped"
+func (m *MediaController) playbackStatus(player string) (string, error) {
+	variant, err := m.conn.Object(player, mprisPath).GetProperty(mprisPlayerInterface + ".PlaybackStatus")
+	if err != nil {
+		return "", err
+	}
+	status, _ := variant.Value().(string)
+	return status, nil
+}
+
+// PauseAll pauses every playing player and remembers which ones they were
+func (m *MediaController) PauseAll() error {
+	players, err := m.players()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, player := range players {
+		status, err := m.playbackStatus(player)
+		if err != nil || status != "Playing" {
+			continue
+		}
+		if call := m.conn.Object(player, mprisPath).Call(mprisPlayerInterface+".Pause", 0); call.Err != nil {
+			log.Printf("media: couldn't pause %s: %v", player, call.Err)
+			continue
+		}
+		m.pausedByUs = appendUnique(m.pausedByUs, player)
+	}
+	return nil
+}
+
+// ResumePaused plays the players we paused, leaving ones the user paused alone
+func (m *MediaController) ResumePaused() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, player := range m.pausedByUs {
+		// Skip players that quit or that the user already restarted/stopped
+		status, err := m.playbackStatus(player)
+		if err != nil || status != "Paused" {
+			continue
+		}
+		if call := m.conn.Object(player, mprisPath).Call(mprisPlayerInterface+".Play", 0); call.Err != nil {
+			log.Printf("media: couldn't resume %s: %v", player, call.Err)
+		}
+	}
+	m.pausedByUs = nil
+	return nil
+}
+
+// appendUnique adds value to list if it isn't already there
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
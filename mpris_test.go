@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeMPRISPlayer implements just enough of org.mpris.MediaPlayer2.Player
+type fakeMPRISPlayer struct {
+	mu     sync.Mutex
+	status string
+}
+
+func (p *fakeMPRISPlayer) Play() *dbus.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = "Playing"
+	return nil
+}
+
+func (p *fakeMPRISPlayer) Pause() *dbus.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = "Paused"
+	return nil
+}
+
+func (p *fakeMPRISPlayer) playbackStatus() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// fakeMPRISProperties serves PlaybackStatus over org.freedesktop.DBus.Properties
+type fakeMPRISProperties struct {
+	player *fakeMPRISPlayer
+}
+
+func (p fakeMPRISProperties) Get(iface, property string) (dbus.Variant, *dbus.Error) {
+	if iface == mprisPlayerInterface && property == "PlaybackStatus" {
+		return dbus.MakeVariant(p.player.playbackStatus()), nil
+	}
+	return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", nil)
+}
+
+// startFakeMPRISPlayer registers a player on the private test bus
+func startFakeMPRISPlayer(t *testing.T, address, name, status string) *fakeMPRISPlayer {
+	t.Helper()
+	conn := connectTestBus(t, address)
+	player := &fakeMPRISPlayer{status: status}
+
+	if err := conn.Export(player, mprisPath, mprisPlayerInterface); err != nil {
+		t.Fatalf("Failed to export player: %v", err)
+	}
+	if err := conn.Export(fakeMPRISProperties{player}, mprisPath, "org.freedesktop.DBus.Properties"); err != nil {
+		t.Fatalf("Failed to export properties: %v", err)
+	}
+	reply, err := conn.RequestName(mprisBusPrefix+name, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("Failed to own player name: %v", err)
+	}
+	return player
+}
+
+func TestMediaControllerPausesAndResumesOnlyItsOwn(t *testing.T) {
+	address := startTestBus(t)
+	music := startFakeMPRISPlayer(t, address, "music", "Playing")
+	podcast := startFakeMPRISPlayer(t, address, "podcast", "Paused") // User paused this one
+
+	controller := NewMediaController(connectTestBus(t, address))
+	defer controller.Close()
+
+	if err := controller.PauseAll(); err != nil {
+		t.Fatalf("PauseAll failed: %v", err)
+	}
+	if music.playbackStatus() != "Paused" {
+		t.Errorf("Playing player should be paused, got %s", music.playbackStatus())
+	}
+
+	if err := controller.ResumePaused(); err != nil {
+		t.Fatalf("ResumePaused failed: %v", err)
+	}
+	if music.playbackStatus() != "Playing" {
+		t.Errorf("Player we paused should resume, got %s", music.playbackStatus())
+	}
+	if podcast.playbackStatus() != "Paused" {
+		t.Errorf("Player the user paused should stay paused, got %s", podcast.playbackStatus())
+	}
+}
+
+func TestMediaControllerApplyIsAsync(t *testing.T) {
+	address := startTestBus(t)
+	music := startFakeMPRISPlayer(t, address, "music", "Playing")
+
+	controller := NewMediaController(connectTestBus(t, address))
+	defer controller.Close()
+
+	controller.Apply(MediaPause)
+	waitFor(t, "player to pause", func() bool { return music.playbackStatus() == "Paused" })
+
+	controller.Apply(MediaResume)
+	waitFor(t, "player to resume", func() bool { return music.playbackStatus() == "Playing" })
+}
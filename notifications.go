@@ -1,37 +1,231 @@
 package main
 
 import (
-	"os/exec"
+	"context"
+	"errors"
+	"log"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2/dialog"
+	"github.com/godbus/dbus/v5"
 )
 
-// showSystemNotification sends a system notification (Ubuntu/Linux)
-func showSystemNotification(title, message string) {
-	// Use notify-send command on Ubuntu
-	cmd := exec.Command("notify-send", title, message, "-i", "alarm-clock", "-t", "5000")
-	cmd.Run() // Fire and forget - don't block if it fails
+// freedesktop notification service names
+const (
+	notificationsService   = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+)
+
+// Notification action keys - mapped to control commands in handleNotificationAction
+const (
+	ActionStartNext = "start-next"
+	ActionSnooze    = "snooze"
+	ActionSkipNext  = "skip-next"
+)
+
+// snoozeMinutes is how long the "Snooze" action extends a finished session
+const snoozeMinutes = 5
+
+// notificationCallTimeout stops a hung notification daemon from holding up
+// the timer goroutine
+var notificationCallTimeout = 2 * time.Second
+
+// errNoNotificationDaemon means nobody owns org.freedesktop.Notifications
+var errNoNotificationDaemon = errors.New("no notification daemon on the session bus")
+
+// NotificationAction is a button shown on a desktop notification
+type NotificationAction struct {
+	Key   string
+	Label string
 }
 
-// showAnnoyingPopup creates a modal dialog that ye MUST click
-func showAnnoyingPopup(title, message string) {
-	// Create an information dialog - must be called from UI thread
-	dialog.ShowInformation(title, message, myWindow)
+// DesktopNotifier talks to the notification daemon directly over D-Bus
+type DesktopNotifier struct {
+	conn     *dbus.Conn
+	onAction func(key string)
+	signals  chan *dbus.Signal
+
+	mu     sync.Mutex
+	lastID uint32 // Replaced by the next notification instead of stacking
 }
 
-// triggerAllAlerts - the full annoying experience!
-func triggerAllAlerts() {
-	// 1. System notification (safe from any thread)
-	showSystemNotification("🏴‍☠️ GoModoro Complete!", "Avast! Yer pomodoro session be finished!")
+// Global desktop notifier (nil if there is no session bus)
+var desktopNotifier *DesktopNotifier
+
+// NewDesktopNotifier wraps a bus connection and listens for action clicks
+func NewDesktopNotifier(conn *dbus.Conn, onAction func(key string)) (*DesktopNotifier, error) {
+	n := &DesktopNotifier{
+		conn:     conn,
+		onAction: onAction,
+		signals:  make(chan *dbus.Signal, 10),
+	}
+
+	err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(notificationsPath),
+		dbus.WithMatchInterface(notificationsInterface),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.Signal(n.signals)
+	go n.listen()
+
+	return n, nil
+}
+
+// Available reports whether a notification daemon is running
+func (n *DesktopNotifier) Available() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationCallTimeout)
+	defer cancel()
+	return n.available(ctx)
+}
+
+func (n *DesktopNotifier) available(ctx context.Context) bool {
+	var hasOwner bool
+	err := n.conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, notificationsService).Store(&hasOwner)
+	return err == nil && hasOwner
+}
+
+// Notify shows (or replaces) the GoModoro notification, giving up after
+// notificationCallTimeout
+func (n *DesktopNotifier) Notify(title, body string, actions []NotificationAction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationCallTimeout)
+	defer cancel()
+	if !n.available(ctx) {
+		return errNoNotificationDaemon
+	}
+
+	// Actions are a flat list of key, label pairs
+	actionList := make([]string, 0, len(actions)*2)
+	for _, action := range actions {
+		actionList = append(actionList, action.Key, action.Label)
+	}
+
+	// Keep notifications with buttons up until the daemon decides otherwise
+	timeout := int32(5000)
+	if len(actions) > 0 {
+		timeout = -1
+	}
+
+	hints := map[string]dbus.Variant{
+		"desktop-entry": dbus.MakeVariant("GoModoro"),
+		"category":      dbus.MakeVariant("x-gomodoro.session"),
+	}
 
-	// 2. Pop-up dialog (must run on UI thread)
-	go func() {
-		time.Sleep(100 * time.Millisecond)
-		showAnnoyingPopup("🍅 Session Complete!",
-			"Ahoy, captain! Yer 25-minute session be done!\n\nTime to take a break and stretch yer sea legs!")
-	}()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-	// 3. Request window focus (safe from any thread)
-	myWindow.RequestFocus()
+	var id uint32
+	obj := n.conn.Object(notificationsService, notificationsPath)
+	call := obj.CallWithContext(ctx, notificationsInterface+".Notify", 0,
+		"GoModoro", n.lastID, "alarm-clock", title, body, actionList, hints, timeout)
+	if err := call.Store(&id); err != nil {
+		return err
+	}
+	n.lastID = id
+	return nil
+}
+
+// listen feeds action clicks on our latest notification back to the app
+func (n *DesktopNotifier) listen() {
+	for signal := range n.signals {
+		switch signal.Name {
+		case notificationsInterface + ".ActionInvoked":
+			if len(signal.Body) < 2 {
+				continue
+			}
+			id, _ := signal.Body[0].(uint32)
+			key, _ := signal.Body[1].(string)
+
+			n.mu.Lock()
+			ours := id != 0 && id == n.lastID
+			n.mu.Unlock()
+
+			if ours && n.onAction != nil {
+				n.onAction(key)
+			}
+		case notificationsInterface + ".NotificationClosed":
+			if len(signal.Body) < 1 {
+				continue
+			}
+			id, _ := signal.Body[0].(uint32)
+
+			n.mu.Lock()
+			if id == n.lastID {
+				n.lastID = 0
+			}
+			n.mu.Unlock()
+		}
+	}
+}
+
+// Close stops listening for signals
+func (n *DesktopNotifier) Close() {
+	n.conn.RemoveSignal(n.signals)
+	close(n.signals)
+}
+
+// setupDesktopNotifications connects to the session bus for notifications
+func setupDesktopNotifications() {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		log.Printf("notifications: no session bus, desktop notifications disabled: %v", err)
+		return
+	}
+
+	notifier, err := NewDesktopNotifier(conn, handleNotificationAction)
+	if err != nil {
+		log.Printf("notifications: %v", err)
+		conn.Close()
+		return
+	}
+	desktopNotifier = notifier
+}
+
+// handleNotificationAction turns a clicked button into a timer command
+func handleNotificationAction(key string) {
+	switch key {
+	case ActionStartNext, ActionSnooze, ActionSkipNext:
+		controlChannel <- key
+	}
+}
+
+// showSystemNotification sends a desktop notification, logging any failure
+func showSystemNotification(title, message string, actions ...NotificationAction) {
+	if desktopNotifier == nil {
+		log.Printf("notifications: %q not shown, desktop notifications unavailable", title)
+		return
+	}
+	if err := desktopNotifier.Notify(title, message, actions); err != nil {
+		log.Printf("notifications: %q not shown: %v", title, err)
+	}
+}
+
+// sessionFinishedActions offers buttons for whatever comes after the current session
+func sessionFinishedActions() []NotificationAction {
+	actions := []NotificationAction{}
+
+	if sessionManager != nil && sessionManager.CurrentIndex+1 < len(sessionManager.Sessions) {
+		next := sessionManager.Sessions[sessionManager.CurrentIndex+1]
+		startLabel := "Start break"
+		skipLabel := "Skip break"
+		if next.Type == SessionWork || next.Type == SessionSurprise {
+			startLabel = "Start next session"
+			skipLabel = "Skip"
+		}
+		actions = append(actions, NotificationAction{Key: ActionStartNext, Label: startLabel})
+		actions = append(actions, NotificationAction{Key: ActionSnooze, Label: "Snooze 5 min"})
+		actions = append(actions, NotificationAction{Key: ActionSkipNext, Label: skipLabel})
+	} else {
+		actions = append(actions, NotificationAction{Key: ActionSnooze, Label: "Snooze 5 min"})
+	}
+	return actions
+}
+
+// showAnnoyingPopup creates a modal dialog that ye MUST click
+func showAnnoyingPopup(title, message string) {
+	// Create an information dialog - must be called from UI thread
+	dialog.ShowInformation(title, message, myWindow)
 }
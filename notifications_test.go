@@ -0,0 +1,198 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// mockNotificationServer records Notify calls like a notification daemon would
+type mockNotificationServer struct {
+	conn *dbus.Conn
+
+	mu      sync.Mutex
+	nextID  uint32
+	calls   []mockNotifyCall
+	visible map[uint32]bool
+}
+
+type mockNotifyCall struct {
+	ReplacesID uint32
+	Summary    string
+	Actions    []string
+}
+
+func (s *mockNotificationServer) Notify(appName string, replacesID uint32, icon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, mockNotifyCall{ReplacesID: replacesID, Summary: summary, Actions: actions})
+
+	id := replacesID
+	if id == 0 || !s.visible[id] {
+		s.nextID++
+		id = s.nextID
+	}
+	s.visible[id] = true
+	return id, nil
+}
+
+// clickAction emits ActionInvoked as if the user pressed a button
+func (s *mockNotificationServer) clickAction(id uint32, key string) error {
+	return s.conn.Emit(notificationsPath, notificationsInterface+".ActionInvoked", id, key)
+}
+
+// startMockNotificationServer claims org.freedesktop.Notifications on the test bus
+func startMockNotificationServer(t *testing.T, address string) *mockNotificationServer {
+	t.Helper()
+	conn := connectTestBus(t, address)
+	server := &mockNotificationServer{conn: conn, visible: map[uint32]bool{}}
+
+	if err := conn.Export(server, notificationsPath, notificationsInterface); err != nil {
+		t.Fatalf("Failed to export mock server: %v", err)
+	}
+	reply, err := conn.RequestName(notificationsService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("Failed to own %s: %v", notificationsService, err)
+	}
+	return server
+}
+
+func TestDesktopNotifierReplacesPrevious(t *testing.T) {
+	address := startTestBus(t)
+	server := startMockNotificationServer(t, address)
+
+	notifier, err := NewDesktopNotifier(connectTestBus(t, address), nil)
+	if err != nil {
+		t.Fatalf("NewDesktopNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	actions := []NotificationAction{
+		{Key: ActionStartNext, Label: "Start break"},
+		{Key: ActionSnooze, Label: "Snooze 5 min"},
+	}
+	if err := notifier.Notify("First", "body", actions); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if err := notifier.Notify("Second", "body", nil); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.calls) != 2 {
+		t.Fatalf("Expected 2 Notify calls, got %d", len(server.calls))
+	}
+	if server.calls[0].ReplacesID != 0 {
+		t.Errorf("First notification should not replace anything")
+	}
+	if server.calls[1].ReplacesID != 1 {
+		t.Errorf("Second notification should replace id 1, got %d", server.calls[1].ReplacesID)
+	}
+	expectedActions := []string{ActionStartNext, "Start break", ActionSnooze, "Snooze 5 min"}
+	if len(server.calls[0].Actions) != len(expectedActions) {
+		t.Fatalf("Expected actions %v, got %v", expectedActions, server.calls[0].Actions)
+	}
+	for i, action := range expectedActions {
+		if server.calls[0].Actions[i] != action {
+			t.Errorf("Expected action %q at %d, got %q", action, i, server.calls[0].Actions[i])
+		}
+	}
+}
+
+func TestDesktopNotifierActionInvoked(t *testing.T) {
+	address := startTestBus(t)
+	server := startMockNotificationServer(t, address)
+
+	clicked := make(chan string, 1)
+	notifier, err := NewDesktopNotifier(connectTestBus(t, address), func(key string) {
+		clicked <- key
+	})
+	if err != nil {
+		t.Fatalf("NewDesktopNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	if err := notifier.Notify("Done", "body", []NotificationAction{{Key: ActionSkipNext, Label: "Skip"}}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	// Clicks on someone else's notification are ignored
+	server.clickAction(99, ActionSnooze)
+	server.clickAction(1, ActionSkipNext)
+
+	select {
+	case key := <-clicked:
+		if key != ActionSkipNext {
+			t.Errorf("Expected %s, got %s", ActionSkipNext, key)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for action callback")
+	}
+}
+
+func TestDesktopNotifierWithoutDaemon(t *testing.T) {
+	address := startTestBus(t)
+
+	notifier, err := NewDesktopNotifier(connectTestBus(t, address), nil)
+	if err != nil {
+		t.Fatalf("NewDesktopNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	if notifier.Available() {
+		t.Error("No daemon is running, notifier should not be available")
+	}
+	if err := notifier.Notify("Nobody home", "body", nil); err != errNoNotificationDaemon {
+		t.Errorf("Expected errNoNotificationDaemon, got %v", err)
+	}
+}
+
+// hungNotificationServer owns the name but never answers Notify
+type hungNotificationServer struct {
+	release chan struct{}
+}
+
+func (s *hungNotificationServer) Notify(appName string, replacesID uint32, icon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	<-s.release
+	return 0, nil
+}
+
+func TestDesktopNotifierGivesUpOnHungDaemon(t *testing.T) {
+	address := startTestBus(t)
+	conn := connectTestBus(t, address)
+	server := &hungNotificationServer{release: make(chan struct{})}
+	defer close(server.release)
+	if err := conn.Export(server, notificationsPath, notificationsInterface); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.RequestName(notificationsService, dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+
+	original := notificationCallTimeout
+	notificationCallTimeout = 100 * time.Millisecond
+	defer func() { notificationCallTimeout = original }()
+
+	notifier, err := NewDesktopNotifier(connectTestBus(t, address), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- notifier.Notify("Stuck", "body", nil) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("A daemon that never answers should be an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify is still waiting on the daemon")
+	}
+}
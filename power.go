@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// PowerEventKind is a lock-screen or suspend transition
+type PowerEventKind string
+
+const (
+	PowerLock   PowerEventKind = "lock"
+	PowerUnlock PowerEventKind = "unlock"
+	PowerSleep  PowerEventKind = "sleep"
+	PowerWake   PowerEventKind = "wake"
+)
+
+// PowerEvent goes to the timer goroutine when the screen locks or the machine sleeps
+type PowerEvent struct {
+	Kind PowerEventKind
+	At   time.Time
+}
+
+// powerChannel carries power events to the timer goroutine
+var powerChannel = make(chan PowerEvent, 4)
+
+// LockSettings decides what the timer does while the screen is locked or the machine sleeps
+type LockSettings struct {
+	PauseWorkOnLock      bool `json:"pause_work_on_lock"`     // Locking pauses a running work session
+	BreaksContinueLocked bool `json:"breaks_continue_locked"` // A locked screen is a natural break
+	ReconcileOnResume    bool `json:"reconcile_on_resume"`    // Count the time asleep against running sessions
+}
+
+// DefaultLockSettings - pause work, let breaks run, and catch the clock up after sleep
+var DefaultLockSettings = LockSettings{
+	PauseWorkOnLock:      true,
+	BreaksContinueLocked: true,
+	ReconcileOnResume:    true,
+}
+
+// Screensaver interfaces that send ActiveChanged(bool)
+var screenSaverInterfaces = []string{"org.freedesktop.ScreenSaver", "org.gnome.ScreenSaver"}
+
+// What happened to the timer when the screen locked or the machine slept (timer goroutine only)
+var (
+	lockedAt  time.Time
+	lockNote  string
+	sleptAt   time.Time
+	sleepNote string
+)
+
+// setupPowerWatch listens for lock and suspend on whichever buses are there
+func setupPowerWatch() {
+	session, err := dbus.ConnectSessionBus()
+	if err != nil {
+		log.Printf("power: no session bus, lock detection disabled: %v", err)
+		session = nil
+	}
+	system, err := dbus.ConnectSystemBus()
+	if err != nil {
+		log.Printf("power: no system bus, suspend detection disabled: %v", err)
+		system = nil
+	}
+	watchPowerSignals(session, system, func(event PowerEvent) { powerChannel <- event })
+}
+
+// watchPowerSignals subscribes to ActiveChanged on session and PrepareForSleep on system
+func watchPowerSignals(session, system *dbus.Conn, report func(PowerEvent)) {
+	if session != nil {
+		for _, iface := range screenSaverInterfaces {
+			if err := session.AddMatchSignal(dbus.WithMatchInterface(iface), dbus.WithMatchMember("ActiveChanged")); err != nil {
+				log.Printf("power: can't watch %s: %v", iface, err)
+			}
+		}
+		forwardPowerSignals(session, report)
+	}
+	if system != nil {
+		if err := system.AddMatchSignal(
+			dbus.WithMatchInterface("org.freedesktop.login1.Manager"),
+			dbus.WithMatchMember("PrepareForSleep"),
+		); err != nil {
+			log.Printf("power: can't watch logind: %v", err)
+		}
+		forwardPowerSignals(system, report)
+	}
+}
+
+// forwardPowerSignals reports power events from one connection until it closes
+func forwardPowerSignals(conn *dbus.Conn, report func(PowerEvent)) {
+	signals := make(chan *dbus.Signal, 10) // Closed by conn.Close
+	conn.Signal(signals)
+	go func() {
+		for signal := range signals {
+			if event, ok := powerEventFromSignal(signal); ok {
+				report(event)
+			}
+		}
+	}()
+}
+
+// powerEventFromSignal turns a D-Bus signal into a power event
+func powerEventFromSignal(signal *dbus.Signal) (PowerEvent, bool) {
+	if len(signal.Body) != 1 {
+		return PowerEvent{}, false
+	}
+	active, ok := signal.Body[0].(bool)
+	if !ok {
+		return PowerEvent{}, false
+	}
+
+	event := PowerEvent{At: time.Now()}
+	switch signal.Name {
+	case "org.freedesktop.login1.Manager.PrepareForSleep":
+		event.Kind = PowerWake
+		if active {
+			event.Kind = PowerSleep
+		}
+	case "org.freedesktop.ScreenSaver.ActiveChanged", "org.gnome.ScreenSaver.ActiveChanged":
+		event.Kind = PowerUnlock
+		if active {
+			event.Kind = PowerLock
+		}
+	default:
+		return PowerEvent{}, false
+	}
+	return event, true
+}
+
+// lockShouldPause decides whether locking (or sleeping) pauses the session
+func lockShouldPause(state string, session *SessionSlot, settings LockSettings) bool {
+	if state != TimerRunning || session == nil {
+		return false
+	}
+	if session.Type == SessionShortBreak || session.Type == SessionLongBreak {
+		return !settings.BreaksContinueLocked
+	}
+	return settings.PauseWorkOnLock
+}
+
+// reconcileRemaining takes the time asleep off the clock
+func reconcileRemaining(remaining int, asleep time.Duration) int {
+	remaining -= int(asleep.Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining
+}
+
+// handlePowerEvent applies the lock policies and records them in history (timer goroutine)
+func handlePowerEvent(event PowerEvent) {
+	switch event.Kind {
+	case PowerLock:
+		if lockedAt.IsZero() {
+			lockedAt = event.At
+			lockNote = applyLockPolicy()
+		}
+	case PowerUnlock:
+		if !lockedAt.IsZero() {
+			recordPowerHistory(HistoryLocked, lockedAt, event.At, lockNote)
+			lockedAt = time.Time{}
+		}
+	case PowerSleep:
+		if sleptAt.IsZero() {
+			sleptAt = event.At
+			sleepNote = applyLockPolicy()
+		}
+	case PowerWake:
+		if sleptAt.IsZero() {
+			return
+		}
+		note := sleepNote
+		// Wall clock time - the monotonic clock (and our ticker) stood still while asleep
+		asleep := event.At.Round(0).Sub(sleptAt.Round(0))
+		if currentState == TimerRunning {
+			if DefaultSettings.Lock.ReconcileOnResume {
+				timeRemaining = reconcileRemaining(timeRemaining, asleep)
+				note += fmt.Sprintf("; took %s asleep off the clock", formatTime(int(asleep.Seconds())))
+				if timeRemaining == 0 {
+					finishSession()
+					note += " and finished it"
+				}
+				updateUI()
+			} else {
+				note += "; clock stood still while asleep"
+			}
+		}
+		recordPowerHistory(HistorySuspended, sleptAt, event.At, note)
+		sleptAt = time.Time{}
+	}
+}
+
+// applyLockPolicy pauses the session if the settings say so and describes what it did
+func applyLockPolicy() string {
+	current := sessionManager.GetCurrentSession()
+	if currentState != TimerRunning || current == nil {
+		return "timer was not running"
+	}
+	if lockShouldPause(currentState, current, DefaultSettings.Lock) {
+		pauseTimer()
+		return "paused " + current.GetSessionLabel()
+	}
+	return "kept " + current.GetSessionLabel() + " running"
+}
+
+// recordPowerHistory logs a lock or sleep against the current session
+func recordPowerHistory(kind HistoryKind, start, end time.Time, note string) {
+	record := HistoryRecord{Kind: kind, Start: start, End: end, Note: note}
+	if current := sessionManager.GetCurrentSession(); current != nil {
+		record.SessionType = current.Type
+		record.SessionNum = current.SessionNum
+		record.Task = current.Task
+	}
+	recordHistory(record)
+}
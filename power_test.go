@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestPowerEventFromSignal(t *testing.T) {
+	tests := []struct {
+		name   string
+		active bool
+		want   PowerEventKind
+	}{
+		{"org.freedesktop.ScreenSaver.ActiveChanged", true, PowerLock},
+		{"org.gnome.ScreenSaver.ActiveChanged", false, PowerUnlock},
+		{"org.freedesktop.login1.Manager.PrepareForSleep", true, PowerSleep},
+		{"org.freedesktop.login1.Manager.PrepareForSleep", false, PowerWake},
+	}
+	for _, tt := range tests {
+		event, ok := powerEventFromSignal(&dbus.Signal{Name: tt.name, Body: []interface{}{tt.active}})
+		if !ok || event.Kind != tt.want {
+			t.Errorf("%s(%v): expected %s, got %s %v", tt.name, tt.active, tt.want, event.Kind, ok)
+		}
+	}
+
+	if _, ok := powerEventFromSignal(&dbus.Signal{Name: "org.example.Other", Body: []interface{}{true}}); ok {
+		t.Error("Unrelated signals should be ignored")
+	}
+}
+
+func TestLockShouldPause(t *testing.T) {
+	work := &SessionSlot{Type: SessionWork}
+	rest := &SessionSlot{Type: SessionLongBreak}
+
+	if !lockShouldPause(TimerRunning, work, DefaultLockSettings) {
+		t.Error("Work should pause on lock by default")
+	}
+	if lockShouldPause(TimerRunning, rest, DefaultLockSettings) {
+		t.Error("Breaks should keep running on lock by default")
+	}
+	if !lockShouldPause(TimerRunning, rest, LockSettings{BreaksContinueLocked: false}) {
+		t.Error("Breaks should pause when they're not allowed to continue")
+	}
+	if lockShouldPause(TimerRunning, work, LockSettings{}) || lockShouldPause(TimerPaused, work, DefaultLockSettings) {
+		t.Error("Nothing to pause")
+	}
+}
+
+func TestReconcileRemaining(t *testing.T) {
+	if got := reconcileRemaining(600, 4*time.Minute); got != 360 {
+		t.Errorf("Expected 360, got %d", got)
+	}
+	if got := reconcileRemaining(60, time.Hour); got != 0 {
+		t.Errorf("Should stop at zero, got %d", got)
+	}
+}
+
+func TestWatchPowerSignals(t *testing.T) {
+	address := startTestBus(t)
+	sender := connectTestBus(t, address)
+
+	events := make(chan PowerEvent, 4)
+	// The test bus stands in for both the session and the system bus
+	watchPowerSignals(connectTestBus(t, address), connectTestBus(t, address), func(event PowerEvent) { events <- event })
+
+	sender.Emit("/org/freedesktop/ScreenSaver", "org.freedesktop.ScreenSaver.ActiveChanged", true)
+	sender.Emit("/org/freedesktop/login1", "org.freedesktop.login1.Manager.PrepareForSleep", true)
+
+	// Each bus has its own goroutine, so the two can arrive in either order
+	got := map[PowerEventKind]bool{}
+	for len(got) < 2 {
+		select {
+		case event := <-events:
+			got[event.Kind] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for events, got %v", got)
+		}
+	}
+	if !got[PowerLock] || !got[PowerSleep] {
+		t.Errorf("Expected lock and sleep, got %v", got)
+	}
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// PresenceSettings shares how we're doing with teammates (and shows how
+// they're doing), so nobody gets interrupted mid-pomodoro. Off until turned on.
+type PresenceSettings struct {
+	Enabled         bool   `json:"enabled"`          // Share our status
+	Channel         string `json:"channel"`          // "lan" or "server" (the sync server)
+	Address         string `json:"address"`          // LAN multicast group (or any UDP address)
+	Team            string `json:"team"`             // Only teammates on the same team show up
+	ShareRemaining  bool   `json:"share_remaining"`  // Include minutes left
+	ShareTask       bool   `json:"share_task"`       // Include what we're working on
+	IntervalSeconds int    `json:"interval_seconds"` // How often to send (and look) when nothing changes
+}
+
+// DefaultPresenceSettings - time left is shared, tasks stay private
+var DefaultPresenceSettings = PresenceSettings{
+	Channel:         "lan",
+	Address:         "239.255.77.17:7718",
+	Team:            "crew",
+	ShareRemaining:  true,
+	IntervalSeconds: 30,
+}
+
+// Statuses teammates see
+const (
+	PresenceWorking = "working"
+	PresenceBreak   = "break"
+	PresencePaused  = "paused"
+	PresenceIdle    = "idle" // Not in a session, or away from the keyboard
+)
+
+// PresenceShare asks the timer goroutine to send our status now, starting
+// to share first if it was just turned on
+const PresenceShare = "presence-share"
+
+// presenceTTL is how long a status counts without being sent again
+var presenceTTL = 2 * time.Minute
+
+// presenceStatus is what one member shares
+type presenceStatus struct {
+	Device  string    `json:"device"`
+	Name    string    `json:"name"`
+	Team    string    `json:"team"`
+	Status  string    `json:"status"`
+	Minutes int       `json:"minutes,omitempty"` // Left in the session, if shared
+	Task    string    `json:"task,omitempty"`    // If shared
+	At      time.Time `json:"at"`                // When it was sent
+
+	seen time.Time // When it arrived here (our clock decides when it's stale)
+}
+
+// PresenceTransport carries statuses between teammates
+type PresenceTransport interface {
+	Publish(status presenceStatus) error
+	Teammates() ([]presenceStatus, error) // Everyone else on the team, by name
+	Close()
+}
+
+// presenceLocal is our state when it last changed, for building statuses
+type presenceLocal struct {
+	Name      string
+	State     string
+	Session   SessionType
+	Remaining int // Seconds
+	At        time.Time
+	Task      string
+	Away      bool // Auto-paused by idle detection
+	Settings  PresenceSettings
+}
+
+// Presence plumbing
+var (
+	presenceUpdates = make(chan presenceLocal, 1)    // Our changes, for the sender
+	presenceChannel = make(chan []presenceStatus, 1) // Teammates, for the timer goroutine
+	presenceStarted bool
+)
+
+// Team panel in the main window
+var (
+	teamLabel         *widget.Label
+	shareCheck        *widget.Check
+	shareRemainingChk *widget.Check
+	shareTaskCheck    *widget.Check
+)
+
+// status builds what we share as of now, leaving out what the user keeps private
+func (l presenceLocal) status(device string, now time.Time) presenceStatus {
+	status := presenceStatus{Device: device, Name: l.Name, Team: l.Settings.Team, Status: PresenceIdle, At: now}
+	remaining := l.Remaining
+	switch l.State {
+	case TimerRunning:
+		status.Status = PresenceWorking
+		if l.Session != SessionWork && l.Session != SessionSurprise {
+			status.Status = PresenceBreak
+		}
+		remaining -= int(now.Sub(l.At).Seconds())
+	case TimerPaused:
+		status.Status = PresencePaused
+		if l.Away {
+			status.Status = PresenceIdle
+		}
+	}
+	if status.Status == PresenceIdle {
+		return status // Nothing more worth knowing
+	}
+	if l.Settings.ShareRemaining && remaining > 0 {
+		status.Minutes = (remaining + 59) / 60
+	}
+	if l.Settings.ShareTask && l.Session == SessionWork {
+		status.Task = l.Task
+	}
+	return status
+}
+
+// localPresence captures our state (timer goroutine)
+func localPresence() presenceLocal {
+	local := presenceLocal{
+		Name:      roomMemberName(),
+		State:     currentState,
+		Remaining: timeRemaining,
+		At:        time.Now(),
+		Task:      currentTask,
+		Away:      idlePaused,
+		Settings:  DefaultSettings.Presence,
+	}
+	if current := sessionManager.GetCurrentSession(); current != nil {
+		local.Session = current.Type
+	}
+	return local
+}
+
+// sharePresence hands our latest state to the sender, replacing any unsent one
+func sharePresence() {
+	if !presenceStarted {
+		return
+	}
+	local := localPresence()
+	select {
+	case <-presenceUpdates:
+	default:
+	}
+	select {
+	case presenceUpdates <- local:
+	default:
+	}
+}
+
+// newPresenceTransport picks the LAN or the sync server
+func newPresenceTransport(settings PresenceSettings, device string) (PresenceTransport, error) {
+	switch settings.Channel {
+	case "", "lan":
+		return newLANPresence(settings.Address, device, settings.Team)
+	case "server":
+		syncSettings := DefaultSettings.Sync
+		if syncSettings.Server == "" {
+			return nil, errors.New(`channel "server" needs a sync server (Sync.server)`)
+		}
+		token := syncSettings.Token
+		if token == "" {
+			token = os.Getenv("GOMODORO_SYNC_TOKEN")
+		}
+		client := NewSyncClient(syncSettings.Server, token, activeProfile, device)
+		return &serverPresence{client: client, team: settings.Team}, nil
+	}
+	return nil, fmt.Errorf("unknown presence channel %q (lan or server)", settings.Channel)
+}
+
+// setupPresence starts sharing with the team, if turned on
+func setupPresence() {
+	if DefaultSettings.Presence.Enabled {
+		startPresence()
+	}
+}
+
+// startPresence connects to the team and keeps it told about our changes
+func startPresence() {
+	if presenceStarted {
+		return
+	}
+	settings := DefaultSettings.Presence
+	device := getDeviceID()
+	transport, err := newPresenceTransport(settings, device)
+	if err != nil {
+		log.Printf("presence: %v", err)
+		return
+	}
+	presenceStarted = true
+	interval := time.Duration(settings.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	go runPresence(transport, device, interval)
+	onLifecycleEvent(func(SessionEvent) {
+		sharePresence()
+	})
+	sharePresence()
+}
+
+// runPresence sends our status on every change and every interval, and
+// passes the team's statuses on to the timer goroutine
+func runPresence(transport PresenceTransport, device string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var local *presenceLocal
+	for {
+		select {
+		case update := <-presenceUpdates:
+			local = &update
+		case <-ticker.C:
+		}
+		if local != nil && local.Settings.Enabled {
+			if err := transport.Publish(local.status(device, time.Now())); err != nil {
+				log.Printf("presence: %v", err)
+			}
+		}
+		teammates, err := transport.Teammates()
+		if err != nil {
+			log.Printf("presence: %v", err)
+			continue
+		}
+		select {
+		case <-presenceChannel:
+		default:
+		}
+		presenceChannel <- teammates
+	}
+}
+
+// freshPresence drops stale statuses and sorts the rest by name
+func freshPresence(statuses map[string]presenceStatus, now time.Time) []presenceStatus {
+	var fresh []presenceStatus
+	for device, status := range statuses {
+		if now.Sub(status.seen) > presenceTTL {
+			delete(statuses, device)
+			continue
+		}
+		fresh = append(fresh, status)
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].Name < fresh[j].Name })
+	return fresh
+}
+
+// lanPresence sends statuses as UDP datagrams (multicast on the LAN) and
+// listens for everyone else's
+type lanPresence struct {
+	device string
+	team   string
+	group  *net.UDPAddr
+	send   *net.UDPConn
+	listen *net.UDPConn
+
+	mu       sync.Mutex
+	statuses map[string]presenceStatus // By device
+}
+
+// newLANPresence joins the multicast group (or listens on a plain UDP address)
+func newLANPresence(address, device, team string) (*lanPresence, error) {
+	group, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	var listen *net.UDPConn
+	if group.IP.IsMulticast() {
+		listen, err = net.ListenMulticastUDP("udp4", nil, group)
+	} else {
+		listen, err = net.ListenUDP("udp4", group)
+	}
+	if err != nil {
+		return nil, err
+	}
+	group = listen.LocalAddr().(*net.UDPAddr) // Fills in a port of 0
+	if !group.IP.IsMulticast() && group.IP.IsUnspecified() {
+		group.IP = net.IPv4(127, 0, 0, 1)
+	}
+	send, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		listen.Close()
+		return nil, err
+	}
+	lan := &lanPresence{device: device, team: team, group: group, send: send, listen: listen, statuses: map[string]presenceStatus{}}
+	go lan.receive()
+	return lan, nil
+}
+
+// receive keeps the latest status from each teammate
+func (l *lanPresence) receive() {
+	buffer := make([]byte, 4096)
+	for {
+		n, _, err := l.listen.ReadFromUDP(buffer)
+		if err != nil {
+			return // Closed
+		}
+		var status presenceStatus
+		if json.Unmarshal(buffer[:n], &status) != nil || status.Device == l.device || status.Team != l.team {
+			continue
+		}
+		status.seen = time.Now()
+		l.mu.Lock()
+		l.statuses[status.Device] = status
+		l.mu.Unlock()
+	}
+}
+
+// Publish sends our status to the group
+func (l *lanPresence) Publish(status presenceStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	_, err = l.send.Write(data)
+	return err
+}
+
+// Teammates is everyone heard from lately
+func (l *lanPresence) Teammates() ([]presenceStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return freshPresence(l.statuses, time.Now()), nil
+}
+
+// Close stops sending and listening
+func (l *lanPresence) Close() {
+	l.send.Close()
+	l.listen.Close()
+}
+
+// serverPresence shares statuses through the sync server
+type serverPresence struct {
+	client *SyncClient
+	team   string
+}
+
+// Publish sends our status to the server
+func (s *serverPresence) Publish(status presenceStatus) error {
+	return s.client.SetPresence(s.team, status)
+}
+
+// Teammates asks the server for everyone else's status
+func (s *serverPresence) Teammates() ([]presenceStatus, error) {
+	return s.client.Presence(s.team)
+}
+
+// Close has nothing to let go of
+func (s *serverPresence) Close() {}
+
+// teamPath is a team's presence on the sync server
+func teamPath(team string) string {
+	return "/v1/team/" + url.PathEscape(team) + "/presence"
+}
+
+// SetPresence shares our status with the team on the server
+func (c *SyncClient) SetPresence(team string, status presenceStatus) error {
+	var reply struct{}
+	return c.do(http.MethodPost, teamPath(team), status, &reply)
+}
+
+// Presence fetches everyone else's status on the team
+func (c *SyncClient) Presence(team string) ([]presenceStatus, error) {
+	var statuses []presenceStatus
+	err := c.do(http.MethodGet, teamPath(team)+"?"+url.Values{"device": {c.device}}.Encode(), nil, &statuses)
+	return statuses, err
+}
+
+// serverTeams is the sync server's memory of who's doing what (never stored)
+type serverTeams struct {
+	mu    sync.Mutex
+	teams map[string]map[string]presenceStatus // Account and team -> device -> status
+}
+
+// presenceTeamKey keeps each token's teams apart, like its sync data, so
+// the same team name under another token is another team
+func presenceTeamKey(r *http.Request) string {
+	token, _ := requestToken(r)
+	return string(accountBucketName(token)) + "/" + r.PathValue("team")
+}
+
+// handleSetPresence remembers a member's status
+func (s *SyncServer) handleSetPresence(w http.ResponseWriter, r *http.Request) {
+	var status presenceStatus
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&status); err != nil || status.Device == "" {
+		http.Error(w, "bad status", http.StatusBadRequest)
+		return
+	}
+	status.Team = r.PathValue("team")
+	status.seen = time.Now()
+	key := presenceTeamKey(r)
+	s.presence.mu.Lock()
+	if s.presence.teams == nil {
+		s.presence.teams = map[string]map[string]presenceStatus{}
+	}
+	if s.presence.teams[key] == nil {
+		s.presence.teams[key] = map[string]presenceStatus{}
+	}
+	s.presence.teams[key][status.Device] = status
+	s.presence.mu.Unlock()
+	writeJSON(w, struct{}{})
+}
+
+// handlePresence lists the team, leaving out the asking device
+func (s *SyncServer) handlePresence(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	s.presence.mu.Lock()
+	statuses := []presenceStatus{}
+	if team := s.presence.teams[presenceTeamKey(r)]; team != nil {
+		for _, status := range freshPresence(team, time.Now()) {
+			if status.Device != device {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	s.presence.mu.Unlock()
+	writeJSON(w, statuses)
+}
+
+// createTeamPanel builds the team list and what-to-share switches
+func createTeamPanel() fyne.CanvasObject {
+	teamLabel = widget.NewLabel("")
+	teamLabel.Wrapping = fyne.TextWrapWord
+	settings := DefaultSettings.Presence
+
+	shareCheck = widget.NewCheck("Share my status", nil)
+	shareRemainingChk = widget.NewCheck("Time left", nil)
+	shareTaskCheck = widget.NewCheck("Task", nil)
+	shareCheck.SetChecked(settings.Enabled)
+	shareRemainingChk.SetChecked(settings.ShareRemaining)
+	shareTaskCheck.SetChecked(settings.ShareTask)
+	onChanged := func(bool) {
+		DefaultSettings.Presence.Enabled = shareCheck.Checked
+		DefaultSettings.Presence.ShareRemaining = shareRemainingChk.Checked
+		DefaultSettings.Presence.ShareTask = shareTaskCheck.Checked
+		updateShareChecks()
+		saveAppState()
+		controlChannel <- PresenceShare
+	}
+	shareCheck.OnChanged = onChanged
+	shareRemainingChk.OnChanged = onChanged
+	shareTaskCheck.OnChanged = onChanged
+	updateShareChecks()
+	updateTeamPanel(nil)
+
+	panel := container.NewVBox(teamLabel, shareCheck, container.NewHBox(widget.NewLabel("Include:"), shareRemainingChk, shareTaskCheck))
+	return widget.NewAccordion(widget.NewAccordionItem("🧭 Crew", panel))
+}
+
+// updateShareChecks only offers the details while sharing
+func updateShareChecks() {
+	if shareCheck.Checked {
+		shareRemainingChk.Enable()
+		shareTaskCheck.Enable()
+	} else {
+		shareRemainingChk.Disable()
+		shareTaskCheck.Disable()
+	}
+}
+
+// updateTeamPanel lists teammates (timer goroutine)
+func updateTeamPanel(teammates []presenceStatus) {
+	if teamLabel == nil {
+		return
+	}
+	switch {
+	case !presenceStarted:
+		teamLabel.SetText("Share yer status to see the crew's.")
+	case len(teammates) == 0:
+		teamLabel.SetText("No crew in sight on " + DefaultSettings.Presence.Team + ".")
+	default:
+		var lines []string
+		for _, teammate := range teammates {
+			lines = append(lines, formatPresence(teammate))
+		}
+		teamLabel.SetText(strings.Join(lines, "\n"))
+	}
+}
+
+// formatPresence is one teammate's line, e.g. "🍅 ana - working, 12 min left (docs)"
+func formatPresence(status presenceStatus) string {
+	icon, activity := "💤", "idle"
+	switch status.Status {
+	case PresenceWorking:
+		icon, activity = "🍅", "working"
+	case PresenceBreak:
+		icon, activity = "☕", "on a break"
+	case PresencePaused:
+		icon, activity = "⏸️", "paused"
+	}
+	line := fmt.Sprintf("%s %s - %s", icon, status.Name, activity)
+	if status.Minutes > 0 {
+		line += fmt.Sprintf(", %d min left", status.Minutes)
+	}
+	if status.Task != "" {
+		line += " (" + status.Task + ")"
+	}
+	return line
+}
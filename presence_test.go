@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPresenceStatusPrivacy(t *testing.T) {
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	private := DefaultPresenceSettings
+	open := DefaultPresenceSettings
+	open.ShareTask = true
+	hidden := DefaultPresenceSettings
+	hidden.ShareRemaining = false
+
+	tests := []struct {
+		name  string
+		local presenceLocal
+		want  presenceStatus
+	}{
+		{"running work counts down from the change",
+			presenceLocal{State: TimerRunning, Session: SessionWork, Remaining: 900, At: now.Add(-2 * time.Minute), Task: "docs", Settings: private},
+			presenceStatus{Status: PresenceWorking, Minutes: 13}},
+		{"task only when shared",
+			presenceLocal{State: TimerRunning, Session: SessionWork, Remaining: 600, At: now, Task: "docs", Settings: open},
+			presenceStatus{Status: PresenceWorking, Minutes: 10, Task: "docs"}},
+		{"time left can be kept private",
+			presenceLocal{State: TimerRunning, Session: SessionShortBreak, Remaining: 300, At: now, Settings: hidden},
+			presenceStatus{Status: PresenceBreak}},
+		{"paused keeps its time",
+			presenceLocal{State: TimerPaused, Session: SessionWork, Remaining: 300, At: now.Add(-time.Hour), Settings: private},
+			presenceStatus{Status: PresencePaused, Minutes: 5}},
+		{"away from the keyboard is idle",
+			presenceLocal{State: TimerPaused, Session: SessionWork, Remaining: 300, Away: true, Task: "docs", Settings: open},
+			presenceStatus{Status: PresenceIdle}},
+		{"between sessions is idle",
+			presenceLocal{State: TimerFinished, Session: SessionWork, Task: "docs", Settings: open},
+			presenceStatus{Status: PresenceIdle}},
+	}
+	for _, test := range tests {
+		test.local.Name = "ana"
+		got := test.local.status("desk", now)
+		want := test.want
+		want.Device, want.Name, want.Team, want.At = "desk", "ana", "crew", now
+		if got != want {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, want)
+		}
+	}
+}
+
+func TestLANPresence(t *testing.T) {
+	lan, err := newLANPresence("127.0.0.1:0", "desk", "crew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lan.Close()
+
+	// Our own status comes back to us, but isn't a teammate
+	if err := lan.Publish(presenceStatus{Device: "desk", Name: "me", Team: "crew", Status: PresenceWorking}); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp4", lan.group.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, status := range []presenceStatus{
+		{Device: "laptop", Name: "ben", Team: "crew", Status: PresenceBreak, Minutes: 3},
+		{Device: "other", Name: "cy", Team: "other-crew", Status: PresenceWorking},
+	} {
+		data, _ := json.Marshal(status)
+		conn.Write(data)
+	}
+	conn.Write([]byte("not json"))
+
+	var teammates []presenceStatus
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if teammates, _ = lan.Teammates(); len(teammates) > 0 {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond) // Let any stragglers arrive
+	teammates, _ = lan.Teammates()
+	if len(teammates) != 1 || teammates[0].Name != "ben" || teammates[0].Minutes != 3 {
+		t.Errorf("Expected only ben from our team, got %+v", teammates)
+	}
+
+	// Nothing heard for a while: gone
+	original := presenceTTL
+	presenceTTL = 0
+	defer func() { presenceTTL = original }()
+	if teammates, _ := lan.Teammates(); len(teammates) != 0 {
+		t.Errorf("Stale statuses should drop off, got %+v", teammates)
+	}
+}
+
+func TestServerPresence(t *testing.T) {
+	url := startTestSyncServer(t)
+	desk := &serverPresence{client: NewSyncClient(url, "s3cret", "work", "desk"), team: "crew"}
+	laptop := &serverPresence{client: NewSyncClient(url, "s3cret", "home", "laptop"), team: "crew"}
+	stranger := &serverPresence{client: NewSyncClient(url, "s3cret", "work", "other"), team: "other-crew"}
+
+	for _, publish := range []struct {
+		transport *serverPresence
+		status    presenceStatus
+	}{
+		{desk, presenceStatus{Device: "desk", Name: "ana", Status: PresenceWorking, Minutes: 12, Task: "docs"}},
+		{laptop, presenceStatus{Device: "laptop", Name: "ben", Status: PresenceIdle}},
+		{stranger, presenceStatus{Device: "other", Name: "cy", Status: PresenceBreak}},
+	} {
+		if err := publish.transport.Publish(publish.status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	teammates, err := desk.Teammates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teammates) != 1 || teammates[0].Name != "ben" || teammates[0].Team != "crew" {
+		t.Errorf("Desk should see only ben, got %+v", teammates)
+	}
+	if teammates, _ := laptop.Teammates(); len(teammates) != 1 || teammates[0].Task != "docs" || teammates[0].Minutes != 12 {
+		t.Errorf("Laptop should see ana's status, got %+v", teammates)
+	}
+
+	intruder := &serverPresence{client: NewSyncClient(url, "guess", "work", "x"), team: "crew"}
+	if _, err := intruder.Teammates(); err == nil {
+		t.Error("A bad token shouldn't see the team")
+	}
+
+	// Another account's crew is another crew, even with the same name
+	neighbour := &serverPresence{client: NewSyncClient(url, "old-token", "work", "desk"), team: "crew"}
+	if err := neighbour.Publish(presenceStatus{Device: "desk", Name: "dee", Status: PresenceBreak}); err != nil {
+		t.Fatal(err)
+	}
+	if teammates, _ := neighbour.Teammates(); len(teammates) != 0 {
+		t.Errorf("Another token shouldn't see this crew, got %+v", teammates)
+	}
+	if teammates, _ := laptop.Teammates(); len(teammates) != 1 || teammates[0].Name != "ana" {
+		t.Errorf("Another token shouldn't overwrite ana's status, got %+v", teammates)
+	}
+}
+
+func TestFormatPresence(t *testing.T) {
+	tests := []struct {
+		status presenceStatus
+		want   string
+	}{
+		{presenceStatus{Name: "ana", Status: PresenceWorking, Minutes: 12, Task: "docs"}, "🍅 ana - working, 12 min left (docs)"},
+		{presenceStatus{Name: "ben", Status: PresenceBreak, Minutes: 3}, "☕ ben - on a break, 3 min left"},
+		{presenceStatus{Name: "cy", Status: PresencePaused}, "⏸️ cy - paused"},
+		{presenceStatus{Name: "dee", Status: PresenceIdle}, "💤 dee - idle"},
+	}
+	for _, test := range tests {
+		if got := formatPresence(test.status); got != test.want {
+			t.Errorf("Got %q, want %q", got, test.want)
+		}
+	}
+}
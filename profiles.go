@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultProfile keeps using the top-level config dir, where everything
+// lived before profiles
+const defaultProfile = "default"
+
+// newProfileOption is the profile picker entry that creates a profile
+const newProfileOption = "➕ New profile…"
+
+// Active profile - settings, state and history all come from its directory
+var activeProfile = defaultProfile
+
+// profileNamePattern keeps names safe to use as directory names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,39}$`)
+
+// validateProfileName checks a profile name typed by the user
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("profile name %q should be letters, digits, - and _ (up to 40)", name)
+	}
+	return nil
+}
+
+// getProfileDir returns (and creates) the active profile's directory
+func getProfileDir() string {
+	if activeProfile == defaultProfile {
+		return getConfigDir()
+	}
+	dir := filepath.Join(getConfigDir(), "profiles", activeProfile)
+	os.MkdirAll(dir, 0755)
+	return dir
+}
+
+// listProfiles returns the default profile and every profile directory
+func listProfiles() []string {
+	profiles := []string{defaultProfile}
+	entries, _ := os.ReadDir(filepath.Join(getConfigDir(), "profiles"))
+	var named []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != defaultProfile && validateProfileName(entry.Name()) == nil {
+			named = append(named, entry.Name())
+		}
+	}
+	sort.Strings(named)
+	return append(profiles, named...)
+}
+
+// getLastProfilePath is where the last used profile is remembered
+func getLastProfilePath() string {
+	return filepath.Join(getConfigDir(), "last_profile")
+}
+
+// readLastProfile returns the profile used last time (default if none)
+func readLastProfile() string {
+	data, err := os.ReadFile(getLastProfilePath())
+	name := strings.TrimSpace(string(data))
+	if err != nil || validateProfileName(name) != nil {
+		return defaultProfile
+	}
+	return name
+}
+
+// rememberProfile makes name the profile used when none is asked for
+func rememberProfile(name string) error {
+	return writeFileAtomic(getLastProfilePath(), []byte(name+"\n"), 0644)
+}
+
+// parseProfileFlag takes --profile NAME (or --profile=NAME) off the front of
+// the arguments, so it works with or without a subcommand after it
+func parseProfileFlag(args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", args, nil
+	}
+	switch {
+	case args[0] == "--profile" || args[0] == "-profile":
+		if len(args) < 2 {
+			return "", nil, errors.New("--profile needs a name")
+		}
+		return args[1], args[2:], validateProfileName(args[1])
+	case strings.HasPrefix(args[0], "--profile="), strings.HasPrefix(args[0], "-profile="):
+		_, name, _ := strings.Cut(args[0], "=")
+		return name, args[1:], validateProfileName(name)
+	}
+	return "", args, nil
+}
+
+// selectStartupProfile picks the profile from the flag, or the last one used,
+// and returns the remaining arguments
+func selectStartupProfile(args []string) ([]string, error) {
+	name, rest, err := parseProfileFlag(args)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = readLastProfile()
+	}
+	activeProfile = name
+	return rest, nil
+}
+
+// switchProfile saves the current state and restarts on the other profile,
+// so every setting (hooks, calendars, webhooks...) is picked up afresh
+func switchProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := saveAppState(); err != nil && !errors.Is(err, errStateTooNew) {
+		return fmt.Errorf("saving state before switching: %w", err)
+	}
+	if err := rememberProfile(name); err != nil {
+		return err
+	}
+
+	// The old profile's socket goes with us; the new process opens its own
+	stopControlSocket()
+	err = syscall.Exec(executable, []string{os.Args[0], "--profile", name}, os.Environ())
+	startControlSocket() // Still here, so the exec failed
+	return err
+}
+
+// createProfileSelect builds the profile picker for the main window
+func createProfileSelect() *widget.Select {
+	profileSelect := widget.NewSelect(append(listProfiles(), newProfileOption), nil)
+	profileSelect.SetSelected(activeProfile)
+	profileSelect.OnChanged = func(choice string) {
+		if choice == activeProfile {
+			return
+		}
+		profileSelect.SetSelected(activeProfile) // Until the switch happens
+		if choice == newProfileOption {
+			askNewProfile()
+			return
+		}
+		confirmProfileSwitch(choice)
+	}
+	return profileSelect
+}
+
+// confirmProfileSwitch asks before restarting on another profile
+func confirmProfileSwitch(name string) {
+	message := fmt.Sprintf("Switch to the %q profile?\nYer timer will be saved (paused) and GoModoro restarts.", name)
+	dialog.ShowConfirm("🧭 Change course", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := switchProfile(name); err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+	}, myWindow)
+}
+
+// askNewProfile asks for a name and switches to the new, empty profile
+func askNewProfile() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("side-project")
+	nameEntry.Validator = validateProfileName
+	dialog.ShowForm("➕ New profile", "Create", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := switchProfile(nameEntry.Text); err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+	}, myWindow)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTestProfile makes name the active profile for one test
+func useTestProfile(t *testing.T, name string) {
+	t.Helper()
+	previous := activeProfile
+	activeProfile = name
+	t.Cleanup(func() { activeProfile = previous })
+}
+
+func TestParseProfileFlag(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		profile string
+		rest    string
+		wantErr bool
+	}{
+		{nil, "", "", false},
+		{[]string{"status"}, "", "status", false},
+		{[]string{"--profile", "work", "export", "--days", "7"}, "work", "export --days 7", false},
+		{[]string{"--profile=side-project"}, "side-project", "", false},
+		{[]string{"--profile"}, "", "", true},
+		{[]string{"--profile", "../../etc"}, "", "", true},
+	} {
+		profile, rest, err := parseProfileFlag(tc.args)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", tc.args)
+			}
+			continue
+		}
+		if err != nil || profile != tc.profile || strings.Join(rest, " ") != tc.rest {
+			t.Errorf("%v: got %q %q %v", tc.args, profile, rest, err)
+		}
+	}
+}
+
+func TestProfileDirs(t *testing.T) {
+	configDir := useTestConfigDir(t)
+	useTestProfile(t, defaultProfile)
+
+	// The default profile keeps the files from before profiles
+	if got := getStateFilePath(); got != filepath.Join(configDir, "session_state.json") {
+		t.Errorf("Default profile state at %s", got)
+	}
+
+	useTestProfile(t, "work")
+	workDir := filepath.Join(configDir, "profiles", "work")
+	for _, path := range []string{getStateFilePath(), getHistoryDBPath(), getHistoryPath()} {
+		if filepath.Dir(path) != workDir {
+			t.Errorf("%s should be in %s", path, workDir)
+		}
+	}
+	os.MkdirAll(filepath.Join(configDir, "profiles", "another"), 0755)
+	os.MkdirAll(filepath.Join(configDir, "profiles", "not a profile"), 0755)
+	if got := strings.Join(listProfiles(), " "); got != "default another work" {
+		t.Errorf("Unexpected profiles %q", got)
+	}
+}
+
+func TestProfilesKeepSeparateState(t *testing.T) {
+	useTestConfigDir(t)
+	sessionManager = newTestSessionManager(t, 2)
+
+	useTestProfile(t, "work")
+	timeRemaining = 111
+	saveAppState()
+
+	useTestProfile(t, "side-project")
+	timeRemaining = 222
+	saveAppState()
+
+	useTestProfile(t, "work")
+	if state, err := readAppState(getStateFilePath()); err != nil || state.TimeRemaining != 111 {
+		t.Errorf("Work profile should have its own state, got %+v %v", state, err)
+	}
+}
+
+func TestStartupProfile(t *testing.T) {
+	useTestConfigDir(t)
+	useTestProfile(t, defaultProfile)
+
+	if rest, _ := selectStartupProfile([]string{"status"}); activeProfile != defaultProfile || len(rest) != 1 {
+		t.Errorf("Without a flag or memory, expected the default profile, got %q", activeProfile)
+	}
+	rememberProfile("work")
+	selectStartupProfile(nil)
+	if activeProfile != "work" {
+		t.Errorf("Expected the last used profile, got %q", activeProfile)
+	}
+	selectStartupProfile([]string{"--profile", "side-project"})
+	if activeProfile != "side-project" {
+		t.Errorf("The flag should win, got %q", activeProfile)
+	}
+}
+
+func TestProfilesServeTheirOwnSocket(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	useTestConfigDir(t)
+	sessionManager = newTestSessionManager(t, 2)
+	useTestProfile(t, defaultProfile)
+	if got := filepath.Base(getControlSocketPath()); got != fmt.Sprintf("gomodoro-%d.sock", os.Getuid()) {
+		t.Errorf("Default profile should keep its old socket, got %s", got)
+	}
+
+	// A running work profile isn't what `--profile side-project status` sees
+	useTestProfile(t, "work")
+	currentState, timeRemaining = TimerRunning, 42
+	publishStatus()
+	if err := startControlSocket(); err != nil {
+		t.Fatal(err)
+	}
+	defer stopControlSocket()
+	if status, err := loadStatus(); err != nil || status.Source != "instance" {
+		t.Errorf("Work should reach its own instance, got %+v %v", status, err)
+	}
+	useTestProfile(t, "side-project")
+	if _, err := queryRunningInstance("ping"); err == nil {
+		t.Error("Side project shouldn't reach the work instance")
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// parseReportDay reads a YYYY-MM-DD day in local time
+func parseReportDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// lastDaysStart is midnight at the start of the last N days, including today
+func lastDaysStart(days int) time.Time {
+	year, month, day := time.Now().Date()
+	return time.Date(year, month, day-days+1, 0, 0, 0, 0, time.Local)
+}
+
+// historyColumns are the CSV columns, named after HistoryRecord's JSON tags
+func historyColumns() []string {
+	recordType := reflect.TypeOf(HistoryRecord{})
+	columns := make([]string, 0, recordType.NumField())
+	for i := 0; i < recordType.NumField(); i++ {
+		name, _, _ := strings.Cut(recordType.Field(i).Tag.Get("json"), ",")
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+// writeHistoryCSV writes one row per record with a header row
+func writeHistoryCSV(w io.Writer, records []HistoryRecord) error {
+	out := csv.NewWriter(w)
+	out.Write(historyColumns())
+	for _, record := range records {
+		value := reflect.ValueOf(record)
+		row := make([]string, value.NumField())
+		for i := range row {
+			switch field := value.Field(i).Interface().(type) {
+			case time.Time:
+				row[i] = field.Format(time.RFC3339)
+			default:
+				row[i] = fmt.Sprint(field)
+			}
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeHistoryJSONLines writes one JSON record per line, like history.jsonl
+func writeHistoryJSONLines(w io.Writer, records []HistoryRecord) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportTotals adds up the sessions in one group of a report
+type reportTotals struct {
+	Seconds   int // Time counted down
+	Completed int
+	Skipped   int
+}
+
+// add counts one session record
+func (t *reportTotals) add(record HistoryRecord) {
+	t.Seconds += record.Elapsed
+	if record.Kind == HistorySkipped {
+		t.Skipped++
+	} else {
+		t.Completed++
+	}
+}
+
+// skipRate is the share of sessions that were skipped, as a percentage
+func (t reportTotals) skipRate() int {
+	if t.Completed+t.Skipped == 0 {
+		return 0
+	}
+	return t.Skipped * 100 / (t.Completed + t.Skipped)
+}
+
+// historyReport groups session records for the Markdown report
+type historyReport struct {
+	From, To time.Time
+	Focus    reportTotals // Work sessions only
+	All      reportTotals
+	Days     map[string]*reportTotals // Work sessions by day
+	Tasks    map[string]*reportTotals // Work sessions by task
+	Types    map[SessionType]*reportTotals
+}
+
+// buildHistoryReport totals up the session records (locks and suspends don't count)
+func buildHistoryReport(records []HistoryRecord) historyReport {
+	report := historyReport{
+		Days:  map[string]*reportTotals{},
+		Tasks: map[string]*reportTotals{},
+		Types: map[SessionType]*reportTotals{},
+	}
+	for _, record := range records {
+		if record.Kind != HistoryCompleted && record.Kind != HistorySkipped {
+			continue
+		}
+		if report.From.IsZero() || record.Start.Before(report.From) {
+			report.From = record.Start
+		}
+		if record.Start.After(report.To) {
+			report.To = record.Start
+		}
+
+		report.All.add(record)
+		totalsFor(report.Types, record.SessionType).add(record)
+		if record.SessionType != SessionWork {
+			continue
+		}
+		report.Focus.add(record)
+		totalsFor(report.Days, record.Start.Local().Format("2006-01-02")).add(record)
+		task := record.Task
+		if task == "" {
+			task = "(no task)"
+		}
+		totalsFor(report.Tasks, task).add(record)
+	}
+	return report
+}
+
+// totalsFor returns the group's totals, creating them on first use
+func totalsFor[K comparable](groups map[K]*reportTotals, key K) *reportTotals {
+	if groups[key] == nil {
+		groups[key] = &reportTotals{}
+	}
+	return groups[key]
+}
+
+// formatReportDuration shows seconds as 1h 05m
+func formatReportDuration(seconds int) string {
+	minutes := seconds / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+// writeHistoryMarkdown writes a summary with totals per day, task and session type
+func writeHistoryMarkdown(w io.Writer, records []HistoryRecord) error {
+	report := buildHistoryReport(records)
+	var b strings.Builder
+
+	if report.All.Completed+report.All.Skipped == 0 {
+		b.WriteString("# 🍅 GoModoro Report\n\nNo sessions in this range.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	fmt.Fprintf(&b, "# 🍅 GoModoro Report: %s to %s\n\n",
+		report.From.Local().Format("2006-01-02"), report.To.Local().Format("2006-01-02"))
+	fmt.Fprintf(&b, "- **Focus:** %s over %d work sessions\n", formatReportDuration(report.Focus.Seconds), report.Focus.Completed)
+	fmt.Fprintf(&b, "- **Skip rate:** %d%% (%d of %d sessions skipped)\n\n",
+		report.All.skipRate(), report.All.Skipped, report.All.Completed+report.All.Skipped)
+
+	b.WriteString("## Per day\n\n| Day | Focus | Work sessions | Skipped |\n|---|---|---|---|\n")
+	for _, day := range sortedKeys(report.Days) {
+		totals := report.Days[day]
+		fmt.Fprintf(&b, "| %s | %s | %d | %d |\n", day, formatReportDuration(totals.Seconds), totals.Completed, totals.Skipped)
+	}
+
+	// Busiest tasks first
+	tasks := sortedKeys(report.Tasks)
+	sort.SliceStable(tasks, func(i, j int) bool { return report.Tasks[tasks[i]].Seconds > report.Tasks[tasks[j]].Seconds })
+	b.WriteString("\n## Per task\n\n| Task | Focus | Work sessions |\n|---|---|---|\n")
+	for _, task := range tasks {
+		totals := report.Tasks[task]
+		fmt.Fprintf(&b, "| %s | %s | %d |\n", escapeMarkdownCell(task), formatReportDuration(totals.Seconds), totals.Completed)
+	}
+
+	b.WriteString("\n## Per session type\n\n| Type | Time | Completed | Skipped | Skip rate |\n|---|---|---|---|---|\n")
+	for _, sessionType := range sortedKeys(report.Types) {
+		totals := report.Types[sessionType]
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d%% |\n", sessionType,
+			formatReportDuration(totals.Seconds), totals.Completed, totals.Skipped, totals.skipRate())
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// escapeMarkdownCell keeps task names from breaking the table
+func escapeMarkdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+}
+
+// Export dialog choices
+var (
+	exportFormatLabels = map[string]string{
+		"Markdown report": "markdown",
+		"CSV":             "csv",
+		"JSON lines":      "json",
+		"iCalendar":       "ics",
+	}
+	exportFormatExtensions = map[string]string{"markdown": ".md", "csv": ".csv", "json": ".jsonl", "ics": ".ics"}
+	exportRangeDays        = map[string]int{"Last 7 days": 7, "Last 30 days": 30, "All time": 0}
+)
+
+// showExportDialog lets the user pick a format and range, then a file to save to
+func showExportDialog() {
+	formatSelect := widget.NewSelect([]string{"Markdown report", "CSV", "JSON lines", "iCalendar"}, nil)
+	formatSelect.SetSelected("Markdown report")
+	rangeSelect := widget.NewSelect([]string{"Last 7 days", "Last 30 days", "All time"}, nil)
+	rangeSelect.SetSelected("Last 7 days")
+
+	form := container.NewVBox(
+		widget.NewLabel("Format:"), formatSelect,
+		widget.NewLabel("Range:"), rangeSelect,
+	)
+	dialog.ShowCustomConfirm("📊 Export yer logbook", "Export", "Cancel", form, func(ok bool) {
+		if !ok {
+			return
+		}
+		format := exportFormatLabels[formatSelect.Selected]
+		from := time.Time{}
+		if days := exportRangeDays[rangeSelect.Selected]; days > 0 {
+			from = lastDaysStart(days)
+		}
+		saveExport(format, from)
+	}, myWindow)
+}
+
+// saveExport asks where to save and writes the export there
+func saveExport(format string, from time.Time) {
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return // Cancelled
+		}
+		defer writer.Close()
+
+		var records []HistoryRecord
+		if historyStore != nil {
+			records, err = historyStore.Query(HistoryQuery{From: from})
+		}
+		if err == nil {
+			err = exportFormatters[format](writer, records)
+		}
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+	}, myWindow)
+	save.SetFileName("gomodoro-" + time.Now().Format("2006-01-02") + exportFormatExtensions[format])
+	save.Show()
+}
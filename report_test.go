@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestWriteHistoryCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeHistoryCSV(&buf, testHistory()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 6 {
+		t.Fatalf("Expected header + 5 rows, got %d", len(rows))
+	}
+	header := strings.Join(rows[0], ",")
+	if header != "id,kind,session_type,session_num,task,start,end,duration,elapsed,note" {
+		t.Errorf("Header should follow the JSON tags, got %s", header)
+	}
+	if rows[1][4] != "Write docs; then, ship" || rows[1][5] != "2024-03-04T09:00:00Z" || rows[1][8] != "1500" {
+		t.Errorf("Unexpected first row %v", rows[1])
+	}
+}
+
+func TestWriteHistoryJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	writeHistoryJSONLines(&buf, testHistory())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], `"session_type":"work"`) {
+		t.Errorf("Unexpected JSON lines:\n%s", buf.String())
+	}
+}
+
+func TestWriteHistoryMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeHistoryMarkdown(&buf, testHistory()); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, want := range []string{
+		"# 🍅 GoModoro Report: 2024-03-04 to 2024-03-04",
+		"- **Focus:** 1h 00m over 2 work sessions", // 25 + 10 (skipped) + 25 minutes
+		"- **Skip rate:** 25% (1 of 4 sessions skipped)",
+		"| Write docs; then, ship | 35m | 1 |",
+		"| (no task) | 25m | 1 |",
+		"| short_break | 5m | 1 | 0 | 0% |",
+		"| work | 1h 00m | 2 | 1 | 33% |",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Missing %q in:\n%s", want, report)
+		}
+	}
+}
+
+func TestWriteHistoryMarkdownEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	writeHistoryMarkdown(&buf, nil)
+	if !strings.Contains(buf.String(), "No sessions") {
+		t.Errorf("Unexpected empty report %q", buf.String())
+	}
+}
@@ -0,0 +1,829 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hashicorp/mdns"
+)
+
+// RoomSettings - team focus rooms, where one GoModoro hosts the cycle and
+// everyone who joins over the LAN follows it
+type RoomSettings struct {
+	Name      string `json:"name"`      // What teammates see (default: your user name)
+	Port      int    `json:"port"`      // Port a hosted room listens on
+	Advertise bool   `json:"advertise"` // Announce hosted rooms over mDNS so they can be found
+}
+
+// DefaultRoomSettings - rooms are found on the LAN by default
+var DefaultRoomSettings = RoomSettings{
+	Port:      7717,
+	Advertise: true,
+}
+
+// Room commands for the timer goroutine
+const (
+	RoomLeave = "room-leave" // Leave the room we're in, or close the one we host
+
+	roomHostPrefix = "room-host:" // + room name: open a room for the crew
+	roomJoinPrefix = "room-join:" // + host:port: join someone's room
+)
+
+// roomService is the mDNS service hosted rooms are announced as
+const roomService = "_gomodoro._tcp"
+
+// Room protocol: one JSON message per line, both ways
+const (
+	roomHello   = "hello"   // Guest -> host: who's joining
+	roomWelcome = "welcome" // Host -> guest: the room's name
+	roomCycle   = "state"   // Host -> guests: the cycle to follow
+	roomRoster  = "members" // Host -> guests: who's in and how they're doing
+	roomStatus  = "status"  // Guest -> host: how the guest is doing
+	roomControl = "control" // Guest -> host: a button pressed (start, pause, skip...)
+
+	// Never sent: how the guest's connection tells the timer goroutine it's up or gone
+	roomUp   = "joined"
+	roomDown = "left"
+)
+
+// roomMessage is one line of the room protocol
+type roomMessage struct {
+	Type    string       `json:"type"`
+	Room    string       `json:"room,omitempty"`    // welcome
+	State   *roomState   `json:"state,omitempty"`   // state
+	Members []roomMember `json:"members,omitempty"` // members
+	Member  *roomMember  `json:"member,omitempty"`  // hello, status
+	Command string       `json:"command,omitempty"` // control
+}
+
+// roomState is the host's cycle, as guests should mirror it
+type roomState struct {
+	Sessions      *SessionManager `json:"sessions"`
+	CurrentState  string          `json:"current_state"`
+	TimeRemaining int             `json:"time_remaining"`
+}
+
+// roomMember is someone in the room and what they're up to
+type roomMember struct {
+	Name      string      `json:"name"`
+	Host      bool        `json:"host,omitempty"`
+	State     string      `json:"state"` // Their timer state (a guest's own pause shows here)
+	Session   SessionType `json:"session,omitempty"`
+	Remaining int         `json:"remaining"`
+	Task      string      `json:"task,omitempty"`
+}
+
+// roomCommands are the buttons a guest presses for the whole room
+var roomCommands = map[string]bool{
+	"start": true, "pause": true, "reset": true, "next": true, "skip": true,
+	ActionStartNext: true, ActionSnooze: true, ActionSkipNext: true,
+}
+
+// roomEvent is something from a joined room's connection for the timer goroutine
+type roomEvent struct {
+	Guest   *roomGuest
+	Message roomMessage
+}
+
+// roomChannel hands room messages to the timer goroutine
+var roomChannel = make(chan roomEvent, 8)
+
+// Room bookkeeping (timer goroutine)
+var (
+	roomHosting *roomHost    // Room we host (nil if none)
+	roomJoined  *roomGuest   // Room we've joined (nil if none)
+	roomSolo    *AppState    // Our own cycle, put aside while in someone's room
+	roomMembers []roomMember // Who's in the room we're in
+)
+
+// Room panel in the main window (hidden when not in a room)
+var (
+	roomPanel    *fyne.Container
+	roomLabel    *widget.Label
+	roomLeaveBtn *widget.Button
+)
+
+// roomMemberName is what teammates see us as
+func roomMemberName() string {
+	if DefaultSettings.Rooms.Name != "" {
+		return DefaultSettings.Rooms.Name
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// localRoomMember is how we're doing, for the room's member list
+func localRoomMember() roomMember {
+	member := roomMember{
+		Name:      roomMemberName(),
+		Host:      roomHosting != nil,
+		State:     currentState,
+		Remaining: timeRemaining,
+		Task:      currentTask,
+	}
+	if current := sessionManager.GetCurrentSession(); current != nil {
+		member.Session = current.Type
+	}
+	return member
+}
+
+// localRoomState copies our cycle for the guests
+func localRoomState() roomState {
+	state := roomState{CurrentState: currentState, TimeRemaining: timeRemaining}
+	if data, err := json.Marshal(sessionManager); err == nil {
+		json.Unmarshal(data, &state.Sessions) // A copy, so guests never see it half changed
+	}
+	return state
+}
+
+// roomPeer is a guest's connection to the room we host
+type roomPeer struct {
+	conn net.Conn
+	out  chan roomMessage
+}
+
+// roomHost serves our cycle to everyone who joins
+type roomHost struct {
+	name     string
+	listener net.Listener
+	mdns     *mdns.Server
+
+	mu      sync.Mutex
+	peers   map[*roomPeer]roomMember
+	self    roomMember
+	state   roomState
+	stateAt time.Time // When state was taken, so newcomers get the right time left
+}
+
+// startRoomHost opens a room on address, starting from our current cycle
+func startRoomHost(name, address string) (*roomHost, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	host := &roomHost{
+		name:     name,
+		listener: listener,
+		peers:    map[*roomPeer]roomMember{},
+		self:     localRoomMember(),
+		state:    localRoomState(),
+		stateAt:  time.Now(),
+	}
+	host.self.Host = true
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return // Room closed
+			}
+			go host.serve(conn)
+		}
+	}()
+	return host, nil
+}
+
+// advertise announces the room over mDNS; it can still be joined by address without
+func (h *roomHost) advertise() error {
+	port := h.listener.Addr().(*net.TCPAddr).Port
+	service, err := mdns.NewMDNSService(h.name, roomService, "", "", port, nil, []string{"room=" + h.name})
+	if err != nil {
+		return err
+	}
+	server, err := mdns.NewServer(&mdns.Config{Zone: service})
+	if err != nil {
+		return err
+	}
+	h.mdns = server
+	return nil
+}
+
+// Address is where guests can reach the room
+func (h *roomHost) Address() string {
+	return h.listener.Addr().String()
+}
+
+// Close ends the room; every guest goes back to their own timer
+func (h *roomHost) Close() {
+	if h.mdns != nil {
+		h.mdns.Shutdown()
+	}
+	h.listener.Close()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for peer := range h.peers {
+		peer.conn.Close()
+	}
+}
+
+// serve handles one guest until they leave
+func (h *roomHost) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var hello roomMessage
+	if err := readRoomMessage(reader, &hello); err != nil || hello.Type != roomHello || hello.Member == nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	peer := &roomPeer{conn: conn, out: make(chan roomMessage, 32)}
+	go peer.write()
+	defer close(peer.out)
+
+	h.mu.Lock()
+	member := *hello.Member
+	member.Host = false
+	h.peers[peer] = member
+	state := h.state
+	if state.CurrentState == TimerRunning {
+		state.TimeRemaining -= int(time.Since(h.stateAt).Seconds())
+	}
+	peer.send(roomMessage{Type: roomWelcome, Room: h.name})
+	peer.send(roomMessage{Type: roomCycle, State: &state})
+	h.mu.Unlock()
+	log.Printf("rooms: %s joined from %s", member.Name, conn.RemoteAddr())
+	h.shareMembers()
+
+	for {
+		var message roomMessage
+		if err := readRoomMessage(reader, &message); err != nil {
+			break
+		}
+		switch message.Type {
+		case roomStatus:
+			if message.Member != nil {
+				h.mu.Lock()
+				status := *message.Member
+				status.Host = false
+				h.peers[peer] = status
+				h.mu.Unlock()
+				h.shareMembers()
+			}
+		case roomControl:
+			if roomCommands[message.Command] {
+				controlChannel <- message.Command // Same as pressing it here
+			}
+		}
+	}
+
+	h.mu.Lock()
+	delete(h.peers, peer)
+	h.mu.Unlock()
+	log.Printf("rooms: %s left", member.Name)
+	h.shareMembers()
+}
+
+// publish sends our new cycle and status to every guest
+func (h *roomHost) publish(state roomState, self roomMember) {
+	h.mu.Lock()
+	h.state, h.stateAt, h.self = state, time.Now(), self
+	for peer := range h.peers {
+		peer.send(roomMessage{Type: roomCycle, State: &state})
+	}
+	h.mu.Unlock()
+	h.shareMembers()
+}
+
+// members lists the host first, then the guests by name
+func (h *roomHost) members() []roomMember {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var guests []roomMember
+	for _, member := range h.peers {
+		guests = append(guests, member)
+	}
+	sort.Slice(guests, func(i, j int) bool { return guests[i].Name < guests[j].Name })
+	return append([]roomMember{h.self}, guests...)
+}
+
+// shareMembers tells everyone (ourselves included) who's in the room
+func (h *roomHost) shareMembers() {
+	members := h.members()
+	h.mu.Lock()
+	for peer := range h.peers {
+		peer.send(roomMessage{Type: roomRoster, Members: members})
+	}
+	h.mu.Unlock()
+	select {
+	case roomChannel <- roomEvent{Message: roomMessage{Type: roomRoster, Members: members}}:
+	default: // The next change will bring it up to date
+	}
+}
+
+// send queues a message; a guest too slow to keep up is dropped
+func (p *roomPeer) send(message roomMessage) {
+	select {
+	case p.out <- message:
+	default:
+		p.conn.Close()
+	}
+}
+
+// write sends queued messages until the connection is done with
+func (p *roomPeer) write() {
+	encoder := json.NewEncoder(p.conn)
+	for message := range p.out {
+		p.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := encoder.Encode(message); err != nil {
+			p.conn.Close()
+		}
+	}
+}
+
+// readRoomMessage reads one line of the room protocol
+func readRoomMessage(reader *bufio.Reader, message *roomMessage) error {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, message)
+}
+
+// roomGuest is our connection to someone else's room
+type roomGuest struct {
+	address string
+	conn    net.Conn
+	mu      sync.Mutex
+	room    string // Name the host gave it
+	leaving bool   // We left, so nobody needs telling
+}
+
+// joinRoom connects to the room at address and introduces us
+func joinRoom(address string, member roomMember) (*roomGuest, error) {
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	guest := &roomGuest{address: address, conn: conn, room: address}
+	if err := guest.send(roomMessage{Type: roomHello, Member: &member}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go guest.read()
+	return guest, nil
+}
+
+// send writes one message to the host
+func (g *roomGuest) send(message roomMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = g.conn.Write(append(data, '\n'))
+	return err
+}
+
+// read passes the host's messages to the timer goroutine until the room ends
+func (g *roomGuest) read() {
+	roomChannel <- roomEvent{Guest: g, Message: roomMessage{Type: roomUp}}
+	reader := bufio.NewReader(g.conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var message roomMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			log.Printf("rooms: garbled message from %s: %v", g.address, err)
+			continue
+		}
+		roomChannel <- roomEvent{Guest: g, Message: message}
+	}
+	g.conn.Close()
+	roomChannel <- roomEvent{Guest: g, Message: roomMessage{Type: roomDown}}
+}
+
+// Leave disconnects; the timer goroutine puts our own cycle back when it hears
+func (g *roomGuest) Leave() {
+	g.mu.Lock()
+	g.leaving = true
+	g.mu.Unlock()
+	g.conn.Close()
+}
+
+// setupRooms keeps hosted rooms and joined rooms told about our changes
+func setupRooms() {
+	onLifecycleEvent(func(SessionEvent) {
+		shareRoomState()
+		if roomJoined != nil {
+			status := localRoomMember()
+			if err := roomJoined.send(roomMessage{Type: roomStatus, Member: &status}); err != nil {
+				log.Printf("rooms: %v", err)
+			}
+		}
+	})
+}
+
+// shareRoomState sends our cycle to the room we host, if any (timer goroutine)
+func shareRoomState() {
+	if roomHosting != nil {
+		roomHosting.publish(localRoomState(), localRoomMember())
+	}
+}
+
+// forwardRoomCommand sends a button press to the host of the room we're in,
+// so the whole room starts, pauses and skips together (timer goroutine)
+func forwardRoomCommand(command string) bool {
+	if roomJoined == nil || !roomCommands[command] {
+		return false
+	}
+	if err := roomJoined.send(roomMessage{Type: roomControl, Command: command}); err != nil {
+		log.Printf("rooms: %v", err)
+	}
+	return true
+}
+
+// handleRoomEvent follows the room we're in (timer goroutine)
+func handleRoomEvent(event roomEvent) {
+	message := event.Message
+	if event.Guest == nil {
+		// From the room we host
+		if message.Type == roomRoster && roomHosting != nil {
+			roomMembers = message.Members
+			updateRoomPanel()
+		}
+		return
+	}
+
+	switch message.Type {
+	case roomUp:
+		if roomJoined != nil && roomJoined != event.Guest {
+			roomJoined.Leave() // Only one room at a time
+		}
+		if roomSolo == nil {
+			solo := currentAppState()
+			if solo.CurrentState == TimerRunning {
+				solo.CurrentState = TimerPaused // Waits for us while we're away
+			}
+			roomSolo = &solo
+		}
+		roomJoined = event.Guest
+		roomMembers = nil
+		updateRoomPanel()
+		return
+	case roomDown:
+		if event.Guest == roomJoined {
+			leaveRoom(!event.Guest.leaving)
+		}
+		return
+	}
+	if event.Guest != roomJoined {
+		return // Left over from a room we've already left
+	}
+
+	switch message.Type {
+	case roomWelcome:
+		event.Guest.room = message.Room
+		updateRoomPanel()
+	case roomCycle:
+		if message.State != nil {
+			applyRoomState(*message.State)
+		}
+	case roomRoster:
+		roomMembers = message.Members
+		updateRoomPanel()
+	}
+}
+
+// applyRoomState mirrors the host's cycle, firing our own lifecycle events so
+// history, hooks and alerts work as if we'd pressed the buttons (timer goroutine)
+func applyRoomState(state roomState) {
+	if state.Sessions == nil {
+		return
+	}
+	wasRunning := currentState == TimerRunning
+	sameSession := sessionManager != nil && sessionManager.CurrentIndex == state.Sessions.CurrentIndex &&
+		len(sessionManager.Sessions) == len(state.Sessions.Sessions)
+
+	// The host finished: finish here too (unless our own countdown got there first)
+	if state.CurrentState == TimerFinished {
+		if sameSession && wasRunning {
+			finishSession()
+		} else if !sameSession || currentState != TimerFinished {
+			adoptRoomCycle(state)
+			currentState, timeRemaining = TimerFinished, 0
+		}
+		updateUI()
+		return
+	}
+	if sameSession && state.CurrentState == currentState && abs(state.TimeRemaining-timeRemaining) <= 2 {
+		return // Already in step
+	}
+
+	adoptRoomCycle(state)
+	currentState, timeRemaining = state.CurrentState, state.TimeRemaining
+	if !sameSession {
+		emitLifecycleEvent(LifecycleSessionChange)
+	}
+	switch {
+	case currentState == TimerRunning:
+		ticker = time.NewTicker(1 * time.Second)
+		if current := sessionManager.GetCurrentSession(); current != nil && current.Type == SessionWork {
+			current.Task = currentTask // Our task, not the host's
+		}
+		if !wasRunning || !sameSession {
+			sessionEvent := newSessionEvent(LifecycleSessionStart)
+			sessionEvent.Resumed = sameSession
+			publishSessionEvent(sessionEvent)
+		}
+	case wasRunning && sameSession && currentState == TimerPaused:
+		emitLifecycleEvent(LifecycleSessionPause)
+	}
+	updateUI()
+}
+
+// adoptRoomCycle takes over the host's session list, stopping our countdown
+func adoptRoomCycle(state roomState) {
+	if ticker != nil {
+		ticker.Stop()
+		ticker = nil
+	}
+	sessionManager = state.Sessions
+}
+
+// abs is the size of a difference in seconds
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// leaveRoom goes back to our own cycle, as it was when we joined (timer goroutine)
+func leaveRoom(tellUser bool) {
+	room := roomJoined.room
+	roomJoined.Leave()
+	roomJoined, roomMembers = nil, nil
+	if ticker != nil {
+		ticker.Stop()
+		ticker = nil
+	}
+	if roomSolo != nil {
+		sessionManager = roomSolo.SessionManagerState
+		currentState = roomSolo.CurrentState
+		timeRemaining = roomSolo.TimeRemaining
+		roomSolo = nil
+	}
+	emitLifecycleEvent(LifecycleSessionChange)
+	saveAppState()
+	updateRoomPanel()
+	updateUI()
+	if tellUser && myWindow != nil {
+		fyne.Do(func() {
+			dialog.ShowInformation("👥 Room closed", fmt.Sprintf("The %s room has ended.\nBack to yer own timer, matey.", room), myWindow)
+		})
+	}
+}
+
+// handleRoomCommand hosts or joins a room as asked from the rooms dialog
+// (timer goroutine)
+func handleRoomCommand(command string) {
+	name, hosting := strings.CutPrefix(command, roomHostPrefix)
+	address, joining := strings.CutPrefix(command, roomJoinPrefix)
+	if !hosting && !joining {
+		return
+	}
+	if roomHosting != nil || roomJoined != nil {
+		if myWindow != nil {
+			fyne.Do(func() {
+				dialog.ShowInformation("👥 Rooms", "Ye're already in a room - leave it first.", myWindow)
+			})
+		}
+		return
+	}
+	if hosting {
+		hostRoom(name)
+		return
+	}
+
+	// Connecting can take a while, so don't hold up the timer; the guest
+	// reports back through roomChannel once it's in
+	member := localRoomMember()
+	go func() {
+		if _, err := joinRoom(address, member); err != nil {
+			log.Printf("rooms: couldn't join %s: %v", address, err)
+			if myWindow != nil {
+				fyne.Do(func() {
+					dialog.ShowError(fmt.Errorf("couldn't join %s: %w", address, err), myWindow)
+				})
+			}
+		}
+	}()
+}
+
+// hostRoom opens a room, announcing it on the LAN if asked to (timer goroutine)
+func hostRoom(name string) {
+	port := DefaultSettings.Rooms.Port
+	if port <= 0 {
+		port = DefaultRoomSettings.Port
+	}
+	host, err := startRoomHost(name, ":"+strconv.Itoa(port))
+	if err != nil {
+		if myWindow != nil {
+			fyne.Do(func() {
+				dialog.ShowError(err, myWindow)
+			})
+		}
+		return
+	}
+	if DefaultSettings.Rooms.Advertise {
+		if err := host.advertise(); err != nil {
+			log.Printf("rooms: announcing %s: %v (it can still be joined by address)", host.name, err)
+		}
+	}
+	roomHosting = host
+	updateRoomPanel()
+}
+
+// closeRoom stops hosting (timer goroutine)
+func closeRoom() {
+	if roomHosting == nil {
+		return
+	}
+	roomHosting.Close()
+	roomHosting, roomMembers = nil, nil
+	updateRoomPanel()
+}
+
+// createRoomPanel builds the member list shown while in a room
+func createRoomPanel() *fyne.Container {
+	roomLabel = widget.NewLabel("")
+	roomLabel.Wrapping = fyne.TextWrapWord
+	roomLeaveBtn = widget.NewButton("🚪 Leave room", func() {
+		controlChannel <- RoomLeave
+	})
+	roomPanel = container.NewVBox(widget.NewSeparator(), roomLabel, roomLeaveBtn)
+	roomPanel.Hide()
+	return roomPanel
+}
+
+// updateRoomPanel shows who's in the room we're in (or host)
+func updateRoomPanel() {
+	if roomPanel == nil {
+		return
+	}
+	switch {
+	case roomHosting != nil:
+		roomLabel.SetText(fmt.Sprintf("👥 Hosting %s on %s\n%s", roomHosting.name, roomHosting.Address(), roomMembersText(roomMembers)))
+		roomLeaveBtn.SetText("🛑 Close room")
+	case roomJoined != nil:
+		roomLabel.SetText(fmt.Sprintf("👥 In %s's room\n%s", roomJoined.room, roomMembersText(roomMembers)))
+		roomLeaveBtn.SetText("🚪 Leave room")
+	default:
+		roomPanel.Hide()
+		return
+	}
+	roomPanel.Show()
+}
+
+// roomMembersText lists members one per line
+func roomMembersText(members []roomMember) string {
+	var lines []string
+	for _, member := range members {
+		lines = append(lines, formatRoomMember(member))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatRoomMember is one member's line, e.g. "⚓ ana - 🍅 working 12:30 (docs)"
+func formatRoomMember(member roomMember) string {
+	icon := "🧑"
+	if member.Host {
+		icon = "⚓"
+	}
+	activity := "working"
+	if member.Session != SessionWork && member.Session != "" {
+		activity = "on a break"
+	}
+	switch member.State {
+	case TimerPaused:
+		activity = "paused"
+	case TimerReady:
+		activity = "ready"
+	case TimerFinished:
+		activity = "done"
+	}
+	line := fmt.Sprintf("%s %s - %s %s", icon, member.Name, activity, formatTime(member.Remaining))
+	if member.Task != "" {
+		line += " (" + member.Task + ")"
+	}
+	return line
+}
+
+// discoverRooms looks for rooms announced on the LAN
+func discoverRooms(timeout time.Duration) []*mdns.ServiceEntry {
+	entries := make(chan *mdns.ServiceEntry, 16)
+	params := mdns.DefaultParams(roomService)
+	params.Entries = entries
+	params.Timeout = timeout
+	params.DisableIPv6 = true
+	go func() {
+		if err := mdns.Query(params); err != nil {
+			log.Printf("rooms: looking for rooms: %v", err)
+		}
+		close(entries)
+	}()
+
+	var found []*mdns.ServiceEntry
+	seen := map[string]bool{}
+	for entry := range entries {
+		if entry.AddrV4 == nil || seen[entry.Name] {
+			continue
+		}
+		seen[entry.Name] = true
+		found = append(found, entry)
+	}
+	return found
+}
+
+// roomEntryName is the room name from an mDNS entry
+func roomEntryName(entry *mdns.ServiceEntry) string {
+	for _, field := range entry.InfoFields {
+		if name, ok := strings.CutPrefix(field, "room="); ok {
+			return name
+		}
+	}
+	return strings.TrimSuffix(entry.Name, "."+roomService+".local.")
+}
+
+// showRoomsDialog hosts a room, or finds one to join
+func showRoomsDialog() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(roomMemberName() + "'s crew")
+	hostBtn := widget.NewButton("⚓ Host a room", nil)
+
+	addressEntry := widget.NewEntry()
+	addressEntry.SetPlaceHolder("192.168.1.20:" + strconv.Itoa(DefaultRoomSettings.Port))
+	found := widget.NewSelect(nil, nil)
+	found.PlaceHolder = "Looking for rooms…"
+	addresses := map[string]string{} // Only touched on the UI goroutine
+	go func() {
+		var names []string
+		discovered := map[string]string{}
+		for _, entry := range discoverRooms(2 * time.Second) {
+			name := roomEntryName(entry)
+			discovered[name] = net.JoinHostPort(entry.AddrV4.String(), strconv.Itoa(entry.Port))
+			names = append(names, name)
+		}
+		fyne.Do(func() {
+			addresses = discovered
+			found.PlaceHolder = "No rooms found - enter an address"
+			if len(names) > 0 {
+				found.PlaceHolder = "Pick a room"
+			}
+			found.SetOptions(names)
+		})
+	}()
+	found.OnChanged = func(name string) {
+		addressEntry.SetText(addresses[name])
+	}
+	joinBtn := widget.NewButton("🧭 Join", nil)
+
+	content := container.NewVBox(
+		widget.NewLabel("Host a room for the crew to follow:"),
+		nameEntry,
+		hostBtn,
+		widget.NewSeparator(),
+		widget.NewLabel("Or join one:"),
+		found,
+		addressEntry,
+		joinBtn,
+	)
+	roomsDialog := dialog.NewCustom("👥 Focus rooms", "Close", content, myWindow)
+
+	hostBtn.OnTapped = func() {
+		roomsDialog.Hide()
+		controlChannel <- roomHostPrefix + strings.TrimSpace(nameEntry.Text)
+	}
+	joinBtn.OnTapped = func() {
+		address := strings.TrimSpace(addressEntry.Text)
+		if address == "" {
+			return
+		}
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, strconv.Itoa(DefaultRoomSettings.Port))
+		}
+		roomsDialog.Hide()
+		controlChannel <- roomJoinPrefix + address
+	}
+	roomsDialog.Show()
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// useTestRoom gives a two-session cycle with harmless alerts, and tidies room state
+func useTestRoom(t *testing.T) {
+	t.Helper()
+	sessionManager = newTestSessionManager(t, 2)
+	useTestConfigDir(t)
+	useTestUI(t)
+	DefaultSettings.AlertRoutes = []AlertRoute{{Event: EventSessionFinished, Notifiers: []string{"desktop"}}}
+	DefaultSettings.Rooms.Name = "ana"
+	currentState, timeRemaining, currentTask = TimerPaused, 600, "docs"
+	t.Cleanup(func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker = nil
+		}
+		roomHosting, roomJoined, roomSolo, roomMembers = nil, nil, nil, nil
+		for len(roomChannel) > 0 {
+			<-roomChannel
+		}
+	})
+}
+
+// testRoomClient is a raw connection speaking the room protocol
+type testRoomClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialTestRoom(t *testing.T, address string, name string) *testRoomClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := &testRoomClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	client.send(roomMessage{Type: roomHello, Member: &roomMember{Name: name, State: TimerReady}})
+	return client
+}
+
+func (c *testRoomClient) send(message roomMessage) {
+	data, _ := json.Marshal(message)
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// next reads messages until one of the given type arrives
+func (c *testRoomClient) next(messageType string) roomMessage {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var message roomMessage
+		if err := readRoomMessage(c.reader, &message); err != nil {
+			c.t.Fatalf("Waiting for %s: %v", messageType, err)
+		}
+		if message.Type == messageType {
+			return message
+		}
+	}
+}
+
+func TestRoomHostServesCycle(t *testing.T) {
+	useTestRoom(t)
+	host, err := startRoomHost("crew", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	roomHosting = host
+
+	guest := dialTestRoom(t, host.Address(), "ben")
+	if welcome := guest.next(roomWelcome); welcome.Room != "crew" {
+		t.Errorf("Expected the room's name, got %+v", welcome)
+	}
+	state := guest.next(roomCycle).State
+	if state == nil || state.CurrentState != TimerPaused || state.TimeRemaining != 600 || len(state.Sessions.Sessions) != 3 {
+		t.Fatalf("Newcomer should get the host's cycle, got %+v", state)
+	}
+	members := guest.next(roomRoster).Members
+	if len(members) != 2 || members[0].Name != "ana" || !members[0].Host || members[1].Name != "ben" {
+		t.Errorf("Expected host then guest, got %+v", members)
+	}
+
+	// The host moves on: the guest hears straight away
+	currentState, timeRemaining = TimerRunning, 590
+	shareRoomState()
+	if state := guest.next(roomCycle).State; state.CurrentState != TimerRunning || state.TimeRemaining != 590 {
+		t.Errorf("Expected the running cycle, got %+v", state)
+	}
+
+	// The guest's status shows in the member list
+	guest.send(roomMessage{Type: roomStatus, Member: &roomMember{Name: "ben", State: TimerPaused, Task: "tests"}})
+	members = guest.next(roomRoster).Members
+	for len(members) == 2 && members[1].State != TimerPaused {
+		members = guest.next(roomRoster).Members // The host's own update may come first
+	}
+	if len(members) != 2 || members[1].Task != "tests" {
+		t.Errorf("Expected ben's status, got %+v", members)
+	}
+
+	// The guest's buttons work the host's timer; anything else is ignored
+	guest.send(roomMessage{Type: roomControl, Command: "rm -rf"})
+	guest.send(roomMessage{Type: roomControl, Command: "pause"})
+	select {
+	case command := <-controlChannel:
+		if command != "pause" {
+			t.Errorf("Expected pause, got %q", command)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Guest's pause never reached the timer")
+	}
+}
+
+func TestRoomGuestFollowsHost(t *testing.T) {
+	useTestRoom(t)
+	setupRooms()
+	var events []LifecycleEvent
+	onLifecycleEvent(func(sessionEvent SessionEvent) { events = append(events, sessionEvent.Event) })
+	ours, theirs := net.Pipe()
+	defer theirs.Close()
+	guest := &roomGuest{address: "pipe", conn: ours, room: "pipe"}
+	sent := make(chan roomMessage, 8)
+	go func() {
+		reader := bufio.NewReader(theirs)
+		for {
+			var message roomMessage
+			if readRoomMessage(reader, &message) != nil {
+				return
+			}
+			sent <- message
+		}
+	}()
+
+	handleRoomEvent(roomEvent{Guest: guest, Message: roomMessage{Type: roomUp}})
+	handleRoomEvent(roomEvent{Guest: guest, Message: roomMessage{Type: roomWelcome, Room: "crew"}})
+	if !roomPanel.Visible() {
+		t.Error("Room panel should show once joined")
+	}
+
+	// The host is on the break, running
+	host := localRoomState().Sessions
+	host.NextSession()
+	handleRoomEvent(roomEvent{Guest: guest, Message: roomMessage{Type: roomCycle,
+		State: &roomState{Sessions: host, CurrentState: TimerRunning, TimeRemaining: 200}}})
+	if currentState != TimerRunning || timeRemaining != 200 || sessionManager.CurrentIndex != 1 || ticker == nil {
+		t.Errorf("Should follow the host's running break, got %s %d index %d", currentState, timeRemaining, sessionManager.CurrentIndex)
+	}
+	if len(events) < 2 || events[0] != LifecycleSessionChange || events[1] != LifecycleSessionStart {
+		t.Errorf("Expected change then start, got %v", events)
+	}
+	if state := currentAppState(); state.CurrentState != TimerPaused || state.TimeRemaining != 600 || state.SessionManagerState.CurrentIndex != 0 {
+		t.Errorf("Our own cycle should be what gets saved, got %s %d", state.CurrentState, state.TimeRemaining)
+	}
+	select {
+	case message := <-sent:
+		if message.Type != roomStatus || message.Member.State != TimerRunning || message.Member.Task != "docs" {
+			t.Errorf("Expected our status, got %+v", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Status never sent to the host")
+	}
+
+	// Our buttons go to the host
+	go func() { controlChannel <- "skip" }()
+	if !forwardRoomCommand(<-controlChannel) {
+		t.Error("Skip should be forwarded")
+	}
+	message := <-sent
+	for message.Type == roomStatus {
+		message = <-sent // One per lifecycle event
+	}
+	if message.Type != roomControl || message.Command != "skip" {
+		t.Errorf("Expected skip to go to the host, got %+v", message)
+	}
+
+	// The host finishes: so do we
+	handleRoomEvent(roomEvent{Guest: guest, Message: roomMessage{Type: roomCycle,
+		State: &roomState{Sessions: host, CurrentState: TimerFinished}}})
+	if currentState != TimerFinished || events[len(events)-1] != LifecycleSessionFinish {
+		t.Errorf("Expected to finish with the host, got %s %v", currentState, events)
+	}
+
+	// The host goes away: back to our own timer, as we left it
+	handleRoomEvent(roomEvent{Guest: guest, Message: roomMessage{Type: roomDown}})
+	if roomJoined != nil || currentState != TimerPaused || timeRemaining != 600 || sessionManager.CurrentIndex != 0 {
+		t.Errorf("Expected our paused solo timer back, got %s %d index %d", currentState, timeRemaining, sessionManager.CurrentIndex)
+	}
+	if roomPanel.Visible() || forwardRoomCommand("start") {
+		t.Error("Out of the room: panel hidden and buttons ours again")
+	}
+}
+
+func TestFormatRoomMember(t *testing.T) {
+	tests := []struct {
+		member roomMember
+		want   string
+	}{
+		{roomMember{Name: "ana", Host: true, State: TimerRunning, Session: SessionWork, Remaining: 750, Task: "docs"}, "⚓ ana - working 12:30 (docs)"},
+		{roomMember{Name: "ben", State: TimerRunning, Session: SessionShortBreak, Remaining: 60}, "🧑 ben - on a break 01:00"},
+		{roomMember{Name: "cy", State: TimerPaused, Session: SessionWork, Remaining: 300}, "🧑 cy - paused 05:00"},
+	}
+	for _, test := range tests {
+		if got := formatRoomMember(test.member); got != test.want {
+			t.Errorf("Got %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestRoomCommandsFromDialog(t *testing.T) {
+	useTestRoom(t)
+	free, _ := net.Listen("tcp", "127.0.0.1:0")
+	free.Close()
+	DefaultSettings.Rooms.Port = free.Addr().(*net.TCPAddr).Port
+	DefaultSettings.Rooms.Advertise = false
+
+	handleRoomCommand(roomHostPrefix + "crew")
+	if roomHosting == nil || roomHosting.name != "crew" {
+		t.Fatal("Expected to host the crew room")
+	}
+	hosted := roomHosting
+	handleRoomCommand(roomJoinPrefix + "127.0.0.1:1") // Already in a room
+	if roomHosting != hosted || roomJoined != nil {
+		t.Error("A second room should be refused")
+	}
+	closeRoom()
+
+	// Joining connects in the background and checks in on roomChannel
+	other, err := startRoomHost("other", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	handleRoomCommand(roomJoinPrefix + other.Address())
+	timeout := time.After(5 * time.Second)
+	for roomJoined == nil {
+		select {
+		case event := <-roomChannel: // The other host's roster may come first
+			handleRoomEvent(event)
+		case <-timeout:
+			t.Fatal("Never joined")
+		}
+	}
+	if roomSolo == nil {
+		t.Error("Expected our own cycle kept aside while in the other room")
+	}
+
+	// Leave, and wait for both ends to notice so nothing's left for later tests
+	guest := roomJoined
+	leaveRoom(false)
+	for guestDown, hostAlone := false, false; !guestDown || !hostAlone; {
+		select {
+		case event := <-roomChannel:
+			guestDown = guestDown || (event.Guest == guest && event.Message.Type == roomDown)
+			hostAlone = hostAlone || (event.Guest == nil && event.Message.Type == roomRoster && len(event.Message.Members) == 1)
+		case <-timeout:
+			t.Fatal("The room never noticed us leaving")
+		}
+	}
+}
@@ -24,11 +24,14 @@ const (
 
 // SessionSlot represents a single time slot in the Pomodoro cycle
 type SessionSlot struct {
-	Type       SessionType `json:"type"`
-	Duration   int         `json:"duration"` // in seconds
-	Completed  bool        `json:"completed"`
-	Current    bool        `json:"current"`
-	SessionNum int         `json:"session_num,omitempty"` // Only for work sessions
+	Type          SessionType `json:"type"`
+	Duration      int         `json:"duration"` // in seconds
+	Completed     bool        `json:"completed"`
+	Current       bool        `json:"current"`
+	SessionNum    int         `json:"session_num,omitempty"`    // Only for work sessions
+	WarningsFired []int       `json:"warnings_fired,omitempty"` // Warning thresholds (seconds) already shown
+	Task          string      `json:"task,omitempty"`           // What the user was working on
+	Meeting       string      `json:"meeting,omitempty"`        // Calendar event a long break stands in for
 }
 
 // SessionManager handles the current todo list and session progression
@@ -213,6 +216,9 @@ func (s *SessionSlot) GetSessionLabel() string {
 	case SessionShortBreak:
 		return "☕ Short Break"
 	case SessionLongBreak:
+		if s.Meeting != "" {
+			return "📅 " + s.Meeting
+		}
 		return "🏖️ Long Break"
 	case SessionSurprise:
 		return "⚡ Surprise Task!"
@@ -16,6 +16,7 @@ var (
 	longBreakFreqSelect    *widget.Select
 	surprisesEntry         *widget.Entry
 	surpriseDurationEntry  *widget.Entry
+	minimizeToTrayCheck    *widget.Check
 	settingsWindow         fyne.Window
 )
 
@@ -71,6 +72,10 @@ func createSettingsUI() *fyne.Container {
 	surpriseDurationEntry.SetText(strconv.Itoa(DefaultSettings.SurpriseMinutes))
 	surpriseDurationEntry.SetPlaceHolder("2")
 
+	// Tray setting
+	minimizeToTrayCheck = widget.NewCheck("Minimise to tray when closing the window", nil)
+	minimizeToTrayCheck.SetChecked(DefaultSettings.MinimizeToTray)
+
 	// Save button
 	saveBtn := widget.NewButton("💾 Save & Close", func() {
 		saveSettings()
@@ -104,6 +109,8 @@ func createSettingsUI() *fyne.Container {
 		surpriseDurationLabel,
 		surpriseDurationEntry,
 		widget.NewSeparator(),
+		minimizeToTrayCheck,
+		widget.NewSeparator(),
 		buttonContainer,
 	)
 
@@ -150,6 +157,9 @@ func saveSettings() {
 		DefaultSettings.SurpriseMinutes = surpriseDuration
 	}
 
+	// Window behaviour
+	DefaultSettings.MinimizeToTray = minimizeToTrayCheck.Checked
+
 	// Rebuild session list with new settings
 	if sessionManager != nil {
 		sessionManager = NewSessionManager()
@@ -164,6 +174,9 @@ func saveSettings() {
 		}
 	}
 
+	// Other devices pick the new settings up from the sync folder
+	controlChannel <- SyncSettingsChanged
+
 	// TODO: Save settings to file for persistence
 	// For now, they just update the in-memory defaults
 }
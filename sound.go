@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/sha1"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Sounds shipped inside the binary, referenced as "bundled:<name>"
+//
+//go:embed sounds/*.wav
+var bundledSounds embed.FS
+
+// bundledPrefix marks a SoundCue file as one of the embedded sounds
+const bundledPrefix = "bundled:"
+
+// SoundCue is one configurable sound
+type SoundCue struct {
+	File   string  `json:"file"`   // "bundled:bell" or a path to a WAV/OGG file; empty = off
+	Volume float64 `json:"volume"` // 0.0 - 1.0
+}
+
+// SoundSettings configures all audio
+type SoundSettings struct {
+	Finish          SoundCue      `json:"finish"`           // Played by the "sound" notifier
+	Warning         SoundCue      `json:"warning"`          // Played by the "sound" notifier on warnings
+	Ticking         SoundCue      `json:"ticking"`          // Played every second while running
+	TickingSessions []SessionType `json:"ticking_sessions"` // Session types that tick
+}
+
+// DefaultSoundSettings - a bell at the end, a chime for warnings, no ticking
+var DefaultSoundSettings = SoundSettings{
+	Finish:          SoundCue{File: bundledPrefix + "bell", Volume: 0.8},
+	Warning:         SoundCue{File: bundledPrefix + "chime", Volume: 0.6},
+	Ticking:         SoundCue{File: "", Volume: 0.3},
+	TickingSessions: []SessionType{SessionWork},
+}
+
+// SoundClip is undecoded audio handed to a backend
+type SoundClip struct {
+	Name   string // Stable name used for caching
+	Format string // "wav" or "ogg"
+	Data   []byte
+}
+
+// AudioBackend decodes and plays clips. Play must not block.
+type AudioBackend interface {
+	Name() string
+	Play(clip SoundClip, volume float64) error
+}
+
+// Global audio backend (null until setupAudio finds a player)
+var audioBackend AudioBackend = nullAudioBackend{}
+
+// Loaded clips, so we don't hit the disk every tick
+var (
+	soundClipCache   = map[string]SoundClip{}
+	soundClipCacheMu sync.Mutex
+)
+
+// setupAudio picks the first sound player available on this machine
+func setupAudio() {
+	for _, player := range []string{"paplay", "pw-play", "aplay"} {
+		if path, err := exec.LookPath(player); err == nil {
+			audioBackend = newPlayerAudioBackend(path)
+			return
+		}
+	}
+	log.Printf("sound: no paplay, pw-play or aplay found, sounds disabled")
+}
+
+// loadSoundClip reads a bundled sound or a user file
+func loadSoundClip(ref string) (SoundClip, error) {
+	soundClipCacheMu.Lock()
+	defer soundClipCacheMu.Unlock()
+
+	if clip, ok := soundClipCache[ref]; ok {
+		return clip, nil
+	}
+
+	var data []byte
+	var err error
+	name := ref
+	if strings.HasPrefix(ref, bundledPrefix) {
+		name = strings.TrimPrefix(ref, bundledPrefix) + ".wav"
+		data, err = bundledSounds.ReadFile("sounds/" + name)
+	} else {
+		data, err = os.ReadFile(expandHome(ref))
+	}
+	if err != nil {
+		return SoundClip{}, err
+	}
+
+	var format string
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".wav":
+		format = "wav"
+	case ".ogg", ".oga":
+		format = "ogg"
+	default:
+		return SoundClip{}, fmt.Errorf("unsupported sound file %q (use WAV or OGG)", ref)
+	}
+
+	clip := SoundClip{Name: ref, Format: format, Data: data}
+	soundClipCache[ref] = clip
+	return clip, nil
+}
+
+// expandHome turns a leading ~/ into the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// playSoundCue plays a cue if it is configured
+func playSoundCue(cue SoundCue) error {
+	if cue.File == "" || cue.Volume <= 0 {
+		return nil
+	}
+	clip, err := loadSoundClip(cue.File)
+	if err != nil {
+		return err
+	}
+	return audioBackend.Play(clip, cue.Volume)
+}
+
+// playTimerSounds plays the background tick once per second while the timer counts down
+func playTimerSounds() {
+	current := sessionManager.GetCurrentSession()
+	if current == nil {
+		return
+	}
+	sounds := DefaultSettings.Sounds
+
+	if timeRemaining > 0 {
+		for _, sessionType := range sounds.TickingSessions {
+			if sessionType == current.Type {
+				if err := playSoundCue(sounds.Ticking); err != nil {
+					log.Printf("sound: ticking cue failed: %v", err)
+				}
+				break
+			}
+		}
+	}
+}
+
+// nullAudioBackend swallows everything (no player installed)
+type nullAudioBackend struct{}
+
+func (nullAudioBackend) Name() string { return "null" }
+
+func (nullAudioBackend) Play(clip SoundClip, volume float64) error { return nil }
+
+// playerAudioBackend hands clips to an external player (paplay, pw-play or aplay)
+type playerAudioBackend struct {
+	player string // Full path to the player binary
+	dir    string // Where clips are written for the player to read
+
+	mu    sync.Mutex
+	files map[string]string // Cache key -> file on disk
+}
+
+// newPlayerAudioBackend creates a backend for the given player binary
+func newPlayerAudioBackend(player string) *playerAudioBackend {
+	return &playerAudioBackend{
+		player: player,
+		dir:    filepath.Join(os.TempDir(), fmt.Sprintf("gomodoro-sounds-%d", os.Getuid())),
+		files:  map[string]string{},
+	}
+}
+
+func (b *playerAudioBackend) Name() string { return filepath.Base(b.player) }
+
+// Play writes the clip out once and starts the player on it
+func (b *playerAudioBackend) Play(clip SoundClip, volume float64) error {
+	var args []string
+	var path string
+	var err error
+
+	switch b.Name() {
+	case "paplay":
+		path, err = b.clipFile(clip, clip.Data, "")
+		args = []string{fmt.Sprintf("--volume=%d", int(volume*65536)), path}
+	case "pw-play":
+		path, err = b.clipFile(clip, clip.Data, "")
+		args = []string{fmt.Sprintf("--volume=%.2f", volume), path}
+	default:
+		// aplay has no volume flag and only knows WAV, so scale the samples ourselves
+		if clip.Format != "wav" {
+			return fmt.Errorf("%s can't play %s files", b.Name(), clip.Format)
+		}
+		audio, decodeErr := decodeWAV(clip.Data)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		path, err = b.clipFile(clip, audio.withVolume(volume).encode(), fmt.Sprintf("%.2f", volume))
+		args = []string{"-q", path}
+	}
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(b.player, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// clipFile returns a file holding data, writing it the first time it's needed
+func (b *playerAudioBackend) clipFile(clip SoundClip, data []byte, variant string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := clip.Name + "|" + variant
+	if path, ok := b.files[key]; ok {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(key))
+	path := filepath.Join(b.dir, hex.EncodeToString(sum[:8])+"."+clip.Format)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	b.files[key] = path
+	return path, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordingAudioBackend remembers what it was asked to play
+type recordingAudioBackend struct {
+	played []string
+	volume []float64
+}
+
+func (r *recordingAudioBackend) Name() string { return "recording" }
+
+func (r *recordingAudioBackend) Play(clip SoundClip, volume float64) error {
+	r.played = append(r.played, clip.Name)
+	r.volume = append(r.volume, volume)
+	return nil
+}
+
+// useRecordingAudio swaps in a recording backend for the test
+func useRecordingAudio(t *testing.T) *recordingAudioBackend {
+	t.Helper()
+	original := audioBackend
+	recorder := &recordingAudioBackend{}
+	audioBackend = recorder
+	t.Cleanup(func() {
+		audioBackend = original
+	})
+	return recorder
+}
+
+func TestBundledSoundsDecode(t *testing.T) {
+	for _, name := range []string{"bell", "chime", "tick"} {
+		clip, err := loadSoundClip(bundledPrefix + name)
+		if err != nil {
+			t.Fatalf("Failed to load bundled %s: %v", name, err)
+		}
+		if clip.Format != "wav" {
+			t.Errorf("Expected wav format for %s, got %s", name, clip.Format)
+		}
+		if _, err := decodeWAV(clip.Data); err != nil {
+			t.Errorf("Bundled %s doesn't decode: %v", name, err)
+		}
+	}
+
+	if _, err := loadSoundClip(bundledPrefix + "nope"); err == nil {
+		t.Error("Unknown bundled sound should fail to load")
+	}
+}
+
+func TestWAVVolumeRoundTrip(t *testing.T) {
+	audio := &wavAudio{Channels: 1, SampleRate: 8000, BitsPerSample: 16, Samples: make([]byte, 4)}
+	binary.LittleEndian.PutUint16(audio.Samples[0:], uint16(int16(1000)))
+	binary.LittleEndian.PutUint16(audio.Samples[2:], uint16(0xFFFF&-2000))
+
+	decoded, err := decodeWAV(audio.withVolume(0.5).encode())
+	if err != nil {
+		t.Fatalf("Failed to decode encoded WAV: %v", err)
+	}
+	first := int16(binary.LittleEndian.Uint16(decoded.Samples[0:]))
+	second := int16(binary.LittleEndian.Uint16(decoded.Samples[2:]))
+	if first != 500 || second != -1000 {
+		t.Errorf("Expected samples 500/-1000 at half volume, got %d/%d", first, second)
+	}
+}
+
+func TestPlaySoundCueUserFile(t *testing.T) {
+	recorder := useRecordingAudio(t)
+
+	bell, _ := bundledSounds.ReadFile("sounds/bell.wav")
+	path := filepath.Join(t.TempDir(), "mine.wav")
+	if err := os.WriteFile(path, bell, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := playSoundCue(SoundCue{File: path, Volume: 0.4}); err != nil {
+		t.Fatalf("playSoundCue failed: %v", err)
+	}
+	if err := playSoundCue(SoundCue{File: "", Volume: 1}); err != nil {
+		t.Fatalf("Empty cue should be a no-op, got %v", err)
+	}
+	if err := playSoundCue(SoundCue{File: filepath.Join(t.TempDir(), "song.mp3"), Volume: 1}); err == nil {
+		t.Error("Missing/unsupported file should fail")
+	}
+
+	if len(recorder.played) != 1 || recorder.played[0] != path || recorder.volume[0] != 0.4 {
+		t.Errorf("Unexpected plays %v at %v", recorder.played, recorder.volume)
+	}
+}
+
+func TestPlayTimerSounds(t *testing.T) {
+	recorder := useRecordingAudio(t)
+	sessionManager = newTestSessionManager(t, 2)
+
+	DefaultSettings.Sounds = SoundSettings{
+		Ticking:         SoundCue{File: bundledPrefix + "tick", Volume: 0.2},
+		TickingSessions: []SessionType{SessionWork},
+	}
+
+	timeRemaining = 61
+	playTimerSounds()
+	if len(recorder.played) != 1 || recorder.played[0] != bundledPrefix+"tick" {
+		t.Fatalf("Work session should tick, got %v", recorder.played)
+	}
+
+	// Breaks don't tick
+	sessionManager.NextSession()
+	timeRemaining = 100
+	playTimerSounds()
+	if len(recorder.played) != 1 {
+		t.Errorf("Break should not tick, got %v", recorder.played)
+	}
+}
+
+func TestSoundNotifierPicksCue(t *testing.T) {
+	recorder := useRecordingAudio(t)
+	originalSettings := DefaultSettings
+	defer func() {
+		DefaultSettings = originalSettings
+	}()
+	DefaultSettings.Sounds = DefaultSoundSettings
+
+	soundAlertNotifier{}.Notify(Alert{Event: EventSessionWarning})
+	soundAlertNotifier{}.Notify(Alert{Event: EventSessionFinished})
+
+	expected := []string{DefaultSoundSettings.Warning.File, DefaultSoundSettings.Finish.File}
+	if len(recorder.played) != 2 || recorder.played[0] != expected[0] || recorder.played[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, recorder.played)
+	}
+}
+
+func TestReadAppStateKeepsDefaultSounds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session_state.json")
+	old := `{"current_state": "ready", "time_remaining": 1500, "settings": {"Sessions": 4}}`
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := readAppState(path)
+	if err != nil {
+		t.Fatalf("readAppState failed: %v", err)
+	}
+	if state.Settings.Sessions != 4 {
+		t.Errorf("Expected saved Sessions=4, got %d", state.Settings.Sessions)
+	}
+	if state.Settings.Sounds.Finish.File != DefaultSoundSettings.Finish.File {
+		t.Errorf("Missing sound settings should keep defaults, got %+v", state.Settings.Sounds)
+	}
+}
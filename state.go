@@ -2,28 +2,49 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// stateBackupCount is how many older copies of the state file are kept
+const stateBackupCount = 3
+
+// stateBackupInterval is how often the backups move along (plus once per
+// launch), so they reach back hours rather than a few auto-saves
+var stateBackupInterval = time.Hour
+
+var (
+	saveStateMu     sync.Mutex // Auto-save and the signal handler can save at the same time
+	lastStateBackup time.Time  // When this launch last rotated the backups (guarded by saveStateMu)
+	stateNotice string     // Shown once the window is up if the state file had to be recovered
+	stateFrozen bool       // State file is from a newer version, so leave it alone
+)
+
 // AppState represents the complete application state for persistence
 type AppState struct {
+	SchemaVersion       int              `json:"schema_version"`
 	SessionManagerState *SessionManager  `json:"session_manager"`
 	CurrentState        string           `json:"current_state"`
 	TimeRemaining       int              `json:"time_remaining"`
 	LastSaved           time.Time        `json:"last_saved"`
 	Settings            GoModoroSettings `json:"settings"`
+	CurrentTask         string           `json:"current_task,omitempty"`
+	Window              *WindowState     `json:"window,omitempty"`
 }
 
-// getStateFilePath returns the path where state should be saved
-func getStateFilePath() string {
+// getConfigDir returns (and creates) the gomodoro config directory
+func getConfigDir() string {
 	// Use XDG_CONFIG_HOME or fallback to ~/.config
 	configDir := os.Getenv("XDG_CONFIG_HOME")
 	if configDir == "" {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			return "./gomodoro_state.json" // Fallback to current directory
+			return "." // Fallback to current directory
 		}
 		configDir = filepath.Join(homeDir, ".config")
 	}
@@ -32,44 +53,199 @@ func getStateFilePath() string {
 	goModoroDir := filepath.Join(configDir, "gomodoro")
 	os.MkdirAll(goModoroDir, 0755)
 
-	return filepath.Join(goModoroDir, "session_state.json")
+	return goModoroDir
 }
 
-// saveAppState saves the current application state to disk
-func saveAppState() error {
-	state := AppState{
+// writeFileAtomic replaces path in one step so readers never see half a file,
+// and syncs it so a crash right after doesn't lose it either
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Make the rename itself durable
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// getStateFilePath returns the path where the active profile's state is saved
+func getStateFilePath() string {
+	configDir := getProfileDir()
+	if configDir == "." {
+		return "./gomodoro_state.json" // Fallback to current directory
+	}
+	return filepath.Join(configDir, "session_state.json")
+}
+
+// currentAppState captures the application state as of now
+func currentAppState() AppState {
+	if roomSolo != nil {
+		// In someone's room: our own cycle is what gets saved and synced
+		state := *roomSolo
+		state.LastSaved = time.Now()
+		state.Settings = DefaultSettings
+		state.CurrentTask = currentTask
+		state.Window = currentWindowState()
+		return state
+	}
+	return AppState{
+		SchemaVersion:       stateSchemaVersion,
 		SessionManagerState: sessionManager,
 		CurrentState:        currentState,
 		TimeRemaining:       timeRemaining,
 		LastSaved:           time.Now(),
 		Settings:            DefaultSettings,
+		CurrentTask:         currentTask,
+		Window:              currentWindowState(),
 	}
+}
 
-	data, err := json.MarshalIndent(state, "", "  ")
+// currentWindowState copies the window state for saving
+func currentWindowState() *WindowState {
+	window := windowState
+	return &window
+}
+
+// saveAppState saves the current application state to disk
+func saveAppState() error {
+	if stateFrozen {
+		return errStateTooNew
+	}
+
+	data, err := json.MarshalIndent(currentAppState(), "", "  ")
 	if err != nil {
 		return err
 	}
 
+	saveStateMu.Lock()
+	defer saveStateMu.Unlock()
 	statePath := getStateFilePath()
-	return os.WriteFile(statePath, data, 0644)
+	if lastStateBackup.IsZero() || time.Since(lastStateBackup) >= stateBackupInterval {
+		if rotateStateBackups(statePath) {
+			lastStateBackup = time.Now()
+		}
+	}
+	return writeFileAtomic(statePath, data, 0644)
 }
 
-// loadAppState loads the application state from disk
-func loadAppState() error {
-	statePath := getStateFilePath()
+// stateBackupPath names backup n (1 = newest)
+func stateBackupPath(statePath string, n int) string {
+	return fmt.Sprintf("%s.bak%d", statePath, n)
+}
+
+// rotateStateBackups shifts the backups along and keeps the current file as
+// the newest one - but only if it's readable, so a bad file never pushes out
+// a good backup. Reports whether it made a backup.
+func rotateStateBackups(statePath string) bool {
+	data, err := os.ReadFile(statePath)
+	if err != nil || !json.Valid(data) {
+		return false
+	}
+	for n := stateBackupCount; n > 1; n-- {
+		os.Rename(stateBackupPath(statePath, n-1), stateBackupPath(statePath, n))
+	}
+	if err := writeFileAtomic(stateBackupPath(statePath, 1), data, 0644); err != nil {
+		log.Printf("state: failed to write backup: %v", err)
+		return false
+	}
+	return true
+}
 
+// recoverAppState reads the state file, falling back to the newest readable
+// backup if it's corrupt. The notice says what happened, for the user.
+func recoverAppState(statePath string) (*AppState, string, error) {
+	state, err := readAppState(statePath)
+	if err == nil {
+		return state, "", nil
+	}
+	if errors.Is(err, errStateTooNew) {
+		// Not damaged, just newer than us - restoring a backup over it would lose data
+		notice := fmt.Sprintf("Yer saved state was written by a newer GoModoro, so this one won't touch it.\nStarting afresh without saving; upgrade to pick up where ye left off (%s).", statePath)
+		return nil, notice, err
+	}
+
+	// Keep the broken file around for a post-mortem
+	corruptPath := statePath + ".corrupt"
+	if data, readErr := os.ReadFile(statePath); readErr == nil {
+		os.WriteFile(corruptPath, data, 0644)
+	}
+
+	for n := 1; n <= stateBackupCount; n++ {
+		backupPath := stateBackupPath(statePath, n)
+		backup, backupErr := readAppState(backupPath)
+		if backupErr != nil || backup == nil {
+			continue
+		}
+		// Put the good copy back so a crash before the next save doesn't lose it
+		if data, readErr := os.ReadFile(backupPath); readErr == nil {
+			writeFileAtomic(statePath, data, 0644)
+		}
+		notice := fmt.Sprintf("Yer saved state was damaged, so it was restored from a backup saved %s.\nThe damaged file was kept as %s.",
+			backup.LastSaved.Local().Format("2006-01-02 15:04"), corruptPath)
+		return backup, notice, nil
+	}
+
+	notice := fmt.Sprintf("Yer saved state was damaged and no backup could be read, so ye be starting afresh.\nThe damaged file was kept as %s.", corruptPath)
+	return nil, notice, err
+}
+
+// readAppState reads a saved state file without touching the globals.
+// Returns nil (and no error) when there is no state file yet.
+func readAppState(statePath string) (*AppState, error) {
 	// Check if state file exists
 	if _, err := os.Stat(statePath); os.IsNotExist(err) {
-		return nil // No state file, start fresh
+		return nil, nil // No state file, start fresh
 	}
 
 	data, err := os.ReadFile(statePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var state AppState
+	data, err = migrateState(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from the defaults so settings missing from older files keep sane values
+	state := AppState{Settings: settingsToReadOver()}
 	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	state.Settings.fillMissingMaps()
+	return &state, nil
+}
+
+// loadAppState loads the application state from disk
+func loadAppState() error {
+	state, notice, err := recoverAppState(getStateFilePath())
+	stateNotice = notice
+	stateFrozen = errors.Is(err, errStateTooNew)
+	if err != nil || state == nil {
 		return err
 	}
 
@@ -80,6 +256,10 @@ func loadAppState() error {
 	currentState = state.CurrentState
 	timeRemaining = state.TimeRemaining
 	DefaultSettings = state.Settings
+	currentTask = state.CurrentTask
+	if state.Window != nil {
+		windowState = *state.Window
+	}
 
 	// If we were in a running state, pause instead to avoid confusion
 	if currentState == TimerRunning {
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useTestConfigDir points the config dir (and so the state file) at a temp dir
+func useTestConfigDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	return filepath.Join(dir, "gomodoro")
+}
+
+// useStateBackupInterval sets how often backups rotate, as if freshly launched
+func useStateBackupInterval(t *testing.T, interval time.Duration) {
+	t.Helper()
+	original := stateBackupInterval
+	stateBackupInterval, lastStateBackup = interval, time.Time{}
+	t.Cleanup(func() { stateBackupInterval, lastStateBackup = original, time.Time{} })
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session_state.json")
+	os.WriteFile(path, []byte("old"), 0644)
+
+	if err := writeFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != "new" || info.Mode().Perm() != 0600 {
+		t.Errorf("Got %q with mode %v", data, info.Mode().Perm())
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*tmp*"))
+	if len(leftovers) != 0 {
+		t.Errorf("Temp files left behind: %v", leftovers)
+	}
+}
+
+func TestSaveAppStateKeepsRotatingBackups(t *testing.T) {
+	useTestConfigDir(t)
+	useStateBackupInterval(t, 0) // Every save
+	sessionManager = newTestSessionManager(t, 2)
+	statePath := getStateFilePath()
+
+	for i := 0; i < stateBackupCount+2; i++ {
+		timeRemaining = 100 + i
+		if err := saveAppState(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n := 1; n <= stateBackupCount; n++ {
+		backup, err := readAppState(stateBackupPath(statePath, n))
+		if err != nil || backup == nil {
+			t.Fatalf("Backup %d unreadable: %v", n, err)
+		}
+		// Newest backup is the save before last
+		if want := 100 + stateBackupCount + 1 - n; backup.TimeRemaining != want {
+			t.Errorf("Backup %d: expected %d, got %d", n, want, backup.TimeRemaining)
+		}
+	}
+	if _, err := os.Stat(stateBackupPath(statePath, stateBackupCount+1)); err == nil {
+		t.Error("Should only keep stateBackupCount backups")
+	}
+}
+
+func TestStateBackupsRotateOncePerInterval(t *testing.T) {
+	useTestConfigDir(t)
+	useStateBackupInterval(t, time.Hour)
+	sessionManager = newTestSessionManager(t, 2)
+	statePath := getStateFilePath()
+
+	// Launch: the first save has nothing to back up, the second backs up the first
+	for i := 0; i < 5; i++ {
+		timeRemaining = 100 + i
+		if err := saveAppState(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backup, err := readAppState(stateBackupPath(statePath, 1))
+	if err != nil || backup == nil || backup.TimeRemaining != 100 {
+		t.Fatalf("Expected the launch's first save backed up, got %+v %v", backup, err)
+	}
+	if _, err := os.Stat(stateBackupPath(statePath, 2)); err == nil {
+		t.Error("Auto-saves within the hour shouldn't rotate the backups")
+	}
+
+	// An hour on, the backups move along once more
+	lastStateBackup = lastStateBackup.Add(-time.Hour)
+	saveAppState()
+	backup, _ = readAppState(stateBackupPath(statePath, 1))
+	older, _ := readAppState(stateBackupPath(statePath, 2))
+	if backup == nil || older == nil || backup.TimeRemaining != 104 || older.TimeRemaining != 100 {
+		t.Errorf("Expected backups 104 and 100, got %+v and %+v", backup, older)
+	}
+}
+
+func TestConcurrentSavesLeaveValidState(t *testing.T) {
+	useTestConfigDir(t)
+	sessionManager = newTestSessionManager(t, 4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			saveAppState()
+		}()
+	}
+	wg.Wait()
+
+	if state, err := readAppState(getStateFilePath()); err != nil || state == nil {
+		t.Fatalf("State file unreadable after concurrent saves: %v", err)
+	}
+}
+
+func TestRecoverAppStateFromBackup(t *testing.T) {
+	useTestConfigDir(t)
+	useStateBackupInterval(t, 0) // Every save
+	sessionManager = newTestSessionManager(t, 2)
+	statePath := getStateFilePath()
+
+	timeRemaining = 321
+	saveAppState()
+	timeRemaining = 123
+	saveAppState() // Backup 1 now holds 321
+
+	// A crash mid-write in the old days: truncated JSON
+	os.WriteFile(statePath, []byte(`{"session_manager": {"sessi`), 0644)
+	saveAppState() // Must not rotate the corrupt file into the backups...
+	os.WriteFile(statePath, []byte(`{"session_manager": {"sessi`), 0644)
+
+	state, notice, err := recoverAppState(statePath)
+	if err != nil || state == nil {
+		t.Fatalf("Expected recovery from backup, got %v", err)
+	}
+	if state.TimeRemaining != 321 {
+		t.Errorf("Expected the newest good backup (321), got %d", state.TimeRemaining)
+	}
+	if !strings.Contains(notice, "restored from a backup") {
+		t.Errorf("Unexpected notice %q", notice)
+	}
+	if _, err := os.Stat(statePath + ".corrupt"); err != nil {
+		t.Error("Corrupt file should be kept")
+	}
+	if restored, err := readAppState(statePath); err != nil || restored.TimeRemaining != 321 {
+		t.Errorf("Good copy should be written back, got %v", err)
+	}
+}
+
+func TestRecoverAppStateWithoutBackups(t *testing.T) {
+	dir := useTestConfigDir(t)
+	os.MkdirAll(dir, 0755)
+	statePath := filepath.Join(dir, "session_state.json")
+	os.WriteFile(statePath, []byte("garbage"), 0644)
+
+	state, notice, err := recoverAppState(statePath)
+	if err == nil || state != nil {
+		t.Errorf("Expected failure without backups, got %+v", state)
+	}
+	if !strings.Contains(notice, "starting afresh") {
+		t.Errorf("The user should be told, got %q", notice)
+	}
+}
+
+func TestReadAppStateLeavesDefaultsAlone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session_state.json")
+	saved := fmt.Sprintf(`{"schema_version": %d, "settings": {
+		"Media": {"enabled": true, "on_session": {"long_break": "pause"}},
+		"Hotkeys": {"window": {"skip": "K"}}}}`, stateSchemaVersion)
+	if err := os.WriteFile(path, []byte(saved), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := readAppState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Settings.Media.OnSession) != 1 || len(state.Settings.Hotkeys.Window) != 1 {
+		t.Errorf("Saved maps should replace the defaults, got %v and %v",
+			state.Settings.Media.OnSession, state.Settings.Hotkeys.Window)
+	}
+	if state.Settings.Hotkeys.Global == nil {
+		t.Error("Maps that weren't saved should get their defaults")
+	}
+	if len(DefaultMediaSettings.OnSession) != 3 || DefaultHotkeySettings.Window["skip"] != "S" ||
+		len(DefaultSettings.Media.OnSession) != 3 {
+		t.Errorf("Reading a state file changed the defaults: %v, %v",
+			DefaultMediaSettings.OnSession, DefaultHotkeySettings.Window)
+	}
+}
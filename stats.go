@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Stats window (global so the 📊 button can bring it back to the front)
+var statsWindow fyne.Window
+
+// statsDays is how far back the stats window looks
+const statsDays = 7
+
+// showStatsWindow shows the last week's focus and the busiest tasks
+func showStatsWindow() {
+	if statsWindow != nil {
+		statsWindow.RequestFocus()
+		return
+	}
+	statsWindow = myApp.NewWindow("GoModoro Stats")
+	statsWindow.Resize(fyne.NewSize(350, 450))
+	statsWindow.SetOnClosed(func() { statsWindow = nil })
+
+	title := widget.NewLabel("📊 Yer Ship's Log")
+	title.Alignment = fyne.TextAlignCenter
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	days, tasks := widget.NewLabel(""), widget.NewLabel("")
+	days.TextStyle = fyne.TextStyle{Monospace: true}
+	if historyStore == nil {
+		days.SetText("No history kept, matey.")
+	} else {
+		days.SetText(statsDaysText(historyStore))
+		tasks.SetText(statsTasksText(historyStore))
+	}
+
+	exportBtn := widget.NewButton("📤 Export…", func() {
+		showExportDialog()
+	})
+
+	statsWindow.SetContent(container.NewVBox(
+		title,
+		widget.NewLabel(fmt.Sprintf("Focus, last %d days:", statsDays)),
+		days,
+		widget.NewSeparator(),
+		widget.NewLabel("Top tasks:"),
+		tasks,
+		widget.NewSeparator(),
+		exportBtn,
+	))
+	statsWindow.CenterOnScreen()
+	statsWindow.Show()
+}
+
+// statsDaysText lists focus per day with a bar, today last
+func statsDaysText(store HistoryRepository) string {
+	totals, err := store.DailyTotals(lastDaysStart(statsDays), time.Time{})
+	if err != nil {
+		return "Couldn't read history: " + err.Error()
+	}
+	byDay := map[string]DailyTotal{}
+	for _, total := range totals {
+		byDay[total.Day] = total
+	}
+
+	var b strings.Builder
+	start := lastDaysStart(statsDays)
+	for i := 0; i < statsDays; i++ {
+		day := start.AddDate(0, 0, i)
+		focus := byDay[day.Format("2006-01-02")].Focus
+		bar := strings.Repeat("▇", focus.Seconds/1800) // One block per half hour
+		fmt.Fprintf(&b, "%s %7s %s\n", day.Format("Mon 02"), formatReportDuration(focus.Seconds), bar)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// statsTasksText lists the five tasks with the most focus
+func statsTasksText(store HistoryRepository) string {
+	tasks, err := store.Tasks()
+	if err != nil {
+		return "Couldn't read history: " + err.Error()
+	}
+	if len(tasks) == 0 {
+		return "None yet - name yer task before ye start!"
+	}
+	var lines []string
+	for i, task := range tasks {
+		if i == 5 {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s (%d sessions)", formatReportDuration(task.Focus.Seconds), task.Task, task.Focus.Completed))
+	}
+	return strings.Join(lines, "\n")
+}
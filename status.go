@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// StatusSnapshot is a read-only view of the timer for status bars and scripts
+type StatusSnapshot struct {
+	State         string      `json:"state"`
+	SessionType   SessionType `json:"session_type"`
+	SessionLabel  string      `json:"session_label"`
+	TimeRemaining int         `json:"time_remaining"` // in seconds
+	Duration      int         `json:"duration"`       // in seconds
+	CycleIndex    int         `json:"cycle_index"`    // 1-based position in the cycle
+	CycleLength   int         `json:"cycle_length"`
+	WorkDone      int         `json:"work_done"`
+	WorkTotal     int         `json:"work_total"`
+	Percentage    int         `json:"percentage"` // progress through the whole cycle
+	Source        string      `json:"source"`     // "instance" or "file"
+}
+
+// buildStatusSnapshot works out the status from a session manager and timer values
+func buildStatusSnapshot(sm *SessionManager, state string, remaining int) StatusSnapshot {
+	status := StatusSnapshot{
+		State:         state,
+		TimeRemaining: remaining,
+	}
+	if sm == nil {
+		return status
+	}
+
+	status.CycleIndex = sm.CurrentIndex + 1
+	status.CycleLength = len(sm.Sessions)
+	status.WorkTotal = sm.TotalWorkCount
+
+	totalSeconds := 0
+	doneSeconds := 0
+	for i, session := range sm.Sessions {
+		totalSeconds += session.Duration
+		if session.Type == SessionWork && session.Completed {
+			status.WorkDone++
+		}
+		if i < sm.CurrentIndex {
+			doneSeconds += session.Duration
+		}
+	}
+
+	if current := sm.GetCurrentSession(); current != nil {
+		status.SessionType = current.Type
+		status.SessionLabel = current.GetSessionLabel()
+		status.Duration = current.Duration
+		doneSeconds += current.Duration - remaining
+	} else {
+		status.CycleIndex = len(sm.Sessions)
+	}
+
+	if totalSeconds > 0 {
+		status.Percentage = doneSeconds * 100 / totalSeconds
+	}
+	return status
+}
+
+// currentStatus snapshots the live timer of this instance
+func currentStatus() StatusSnapshot {
+	status := buildStatusSnapshot(sessionManager, currentState, timeRemaining)
+	status.Source = "instance"
+	return status
+}
+
+// publishedStatus is the last status the timer goroutine published, for
+// readers on other goroutines (the control socket)
+var publishedStatus atomic.Value // StatusSnapshot
+
+// publishStatus snapshots the live timer for other goroutines (timer goroutine)
+func publishStatus() {
+	publishedStatus.Store(currentStatus())
+}
+
+// latestStatus is the last published status
+func latestStatus() StatusSnapshot {
+	status, _ := publishedStatus.Load().(StatusSnapshot)
+	return status
+}
+
+// statusFromAppState builds a snapshot from a saved state file
+func statusFromAppState(state *AppState) StatusSnapshot {
+	// Nothing is ticking without a running instance, same as loadAppState does
+	timerState := state.CurrentState
+	if timerState == TimerRunning {
+		timerState = TimerPaused
+	}
+	status := buildStatusSnapshot(state.SessionManagerState, timerState, state.TimeRemaining)
+	status.Source = "file"
+	return status
+}
+
+// statusIcon returns a short glyph for the session type
+func statusIcon(sessionType SessionType) string {
+	switch sessionType {
+	case SessionWork:
+		return "🍅"
+	case SessionShortBreak:
+		return "☕"
+	case SessionLongBreak:
+		return "🏖️"
+	case SessionSurprise:
+		return "⚡"
+	default:
+		return "⏱"
+	}
+}
+
+// statusClass is the CSS-ish class used by Waybar and i3bar styling
+func statusClass(status StatusSnapshot) string {
+	if status.State == "" || status.SessionType == "" {
+		return "idle"
+	}
+	if status.State == TimerRunning {
+		return string(status.SessionType)
+	}
+	return string(status.SessionType) + "-" + status.State
+}
+
+// statusText is the one-line text shared by all formats
+func statusText(status StatusSnapshot) string {
+	if status.SessionType == "" {
+		return "⏱ --:--"
+	}
+	text := fmt.Sprintf("%s %s", statusIcon(status.SessionType), formatTime(status.TimeRemaining))
+	if status.State == TimerPaused {
+		text += " ⏸"
+	}
+	return text
+}
+
+// statusTooltip is the longer description shown on hover
+func statusTooltip(status StatusSnapshot) string {
+	if status.SessionType == "" {
+		return "GoModoro: no session"
+	}
+	return fmt.Sprintf("%s (%s)\nSession %d/%d • Work %d/%d • %d%% of cycle",
+		status.SessionLabel, status.State,
+		status.CycleIndex, status.CycleLength,
+		status.WorkDone, status.WorkTotal,
+		status.Percentage)
+}
+
+// statusColor picks a colour per session type for the colour-aware formats
+func statusColor(status StatusSnapshot) string {
+	if status.State != TimerRunning {
+		return "#888888"
+	}
+	switch status.SessionType {
+	case SessionWork:
+		return "#e06c75"
+	case SessionShortBreak, SessionLongBreak:
+		return "#98c379"
+	case SessionSurprise:
+		return "#e5c07b"
+	default:
+		return "#abb2bf"
+	}
+}
+
+// statusFormatters maps --format names to their output templates
+var statusFormatters = map[string]func(StatusSnapshot) (string, error){
+	"plain": func(s StatusSnapshot) (string, error) {
+		return fmt.Sprintf("%s [%d/%d]", statusText(s), s.CycleIndex, s.CycleLength), nil
+	},
+	"json": func(s StatusSnapshot) (string, error) {
+		data, err := json.Marshal(s)
+		return string(data), err
+	},
+	"waybar": func(s StatusSnapshot) (string, error) {
+		data, err := json.Marshal(map[string]interface{}{
+			"text":       statusText(s),
+			"alt":        string(s.SessionType),
+			"tooltip":    statusTooltip(s),
+			"class":      statusClass(s),
+			"percentage": s.Percentage,
+		})
+		return string(data), err
+	},
+	"i3bar": func(s StatusSnapshot) (string, error) {
+		data, err := json.Marshal(map[string]interface{}{
+			"name":       "gomodoro",
+			"instance":   statusClass(s),
+			"full_text":  fmt.Sprintf("%s [%d/%d]", statusText(s), s.CycleIndex, s.CycleLength),
+			"short_text": statusText(s),
+			"color":      statusColor(s),
+			"tooltip":    statusTooltip(s),
+		})
+		return string(data), err
+	},
+	"tmux": func(s StatusSnapshot) (string, error) {
+		return fmt.Sprintf("#[fg=%s]%s#[default]", statusColor(s), statusText(s)), nil
+	},
+	"polybar": func(s StatusSnapshot) (string, error) {
+		return fmt.Sprintf("%%{F%s}%s%%{F-}", statusColor(s), statusText(s)), nil
+	},
+}
+
+// statusFormatNames lists the available formats for help output
+func statusFormatNames() string {
+	names := make([]string, 0, len(statusFormatters))
+	for name := range statusFormatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// loadStatus asks the running instance first, then falls back to the state file
+func loadStatus() (StatusSnapshot, error) {
+	if reply, err := queryRunningInstance("status"); err == nil {
+		var status StatusSnapshot
+		if err := json.Unmarshal([]byte(reply), &status); err == nil {
+			return status, nil
+		}
+	}
+
+	state, err := readAppState(getStateFilePath())
+	if err != nil {
+		return StatusSnapshot{}, err
+	}
+	if state == nil {
+		return StatusSnapshot{Source: "file"}, nil
+	}
+	return statusFromAppState(state), nil
+}
+
+// runStatusCommand implements `gomodoro status`
+func runStatusCommand(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	format := flags.String("format", "plain", "output format: "+statusFormatNames())
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	formatter, ok := statusFormatters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q (available: %s)\n", *format, statusFormatNames())
+		return 2
+	}
+
+	status, err := loadStatus()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro status:", err)
+		return 1
+	}
+
+	output, err := formatter(status)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro status:", err)
+		return 1
+	}
+	fmt.Println(output)
+	return 0
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newTestSessionManager builds a deterministic cycle (no surprises, no long breaks)
+func newTestSessionManager(t *testing.T, sessions int) *SessionManager {
+	t.Helper()
+	originalSettings := DefaultSettings
+	t.Cleanup(func() {
+		DefaultSettings = originalSettings
+	})
+
+	DefaultSettings = GoModoroSettings{
+		Sessions:           sessions,
+		ShortBreak:         5,
+		LongBreak:          15,
+		LongBreakFrequency: 0,
+		Surprises:          0,
+		SurpriseMinutes:    2,
+	}
+	return NewSessionManager()
+}
+
+func TestBuildStatusSnapshot(t *testing.T) {
+	sm := newTestSessionManager(t, 2) // work, break, work
+	sm.NextSession()                  // now on the short break
+
+	status := buildStatusSnapshot(sm, TimerRunning, 60)
+
+	if status.SessionType != SessionShortBreak {
+		t.Errorf("Expected short break, got %s", status.SessionType)
+	}
+	if status.CycleIndex != 2 || status.CycleLength != 3 {
+		t.Errorf("Expected 2/3, got %d/%d", status.CycleIndex, status.CycleLength)
+	}
+	if status.WorkDone != 1 || status.WorkTotal != 2 {
+		t.Errorf("Expected work 1/2, got %d/%d", status.WorkDone, status.WorkTotal)
+	}
+
+	// 25 min done + 4 of 5 min break done, out of 55 min total
+	if status.Percentage != (25*60+4*60)*100/(55*60) {
+		t.Errorf("Unexpected percentage %d", status.Percentage)
+	}
+}
+
+func TestStatusFromAppStatePausesRunning(t *testing.T) {
+	state := &AppState{
+		SessionManagerState: newTestSessionManager(t, 2),
+		CurrentState:        TimerRunning,
+		TimeRemaining:       600,
+	}
+
+	status := statusFromAppState(state)
+	if status.State != TimerPaused {
+		t.Errorf("Saved running state should read as paused, got %s", status.State)
+	}
+	if status.Source != "file" {
+		t.Errorf("Expected source file, got %s", status.Source)
+	}
+}
+
+func TestStatusFormatters(t *testing.T) {
+	status := buildStatusSnapshot(newTestSessionManager(t, 2), TimerRunning, 1500)
+
+	tests := []struct {
+		format   string
+		contains string
+	}{
+		{"plain", "🍅 25:00 [1/3]"},
+		{"tmux", "#[fg=#e06c75]🍅 25:00#[default]"},
+		{"polybar", "%{F#e06c75}🍅 25:00%{F-}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			output, err := statusFormatters[tt.format](status)
+			if err != nil {
+				t.Fatalf("format failed: %v", err)
+			}
+			if !strings.Contains(output, tt.contains) {
+				t.Errorf("Expected %q in %q", tt.contains, output)
+			}
+		})
+	}
+
+	output, err := statusFormatters["waybar"](status)
+	if err != nil {
+		t.Fatalf("waybar format failed: %v", err)
+	}
+	var waybar map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &waybar); err != nil {
+		t.Fatalf("waybar output is not JSON: %v", err)
+	}
+	if waybar["class"] != "work" || waybar["tooltip"] == "" {
+		t.Errorf("Unexpected waybar output %s", output)
+	}
+}
+
+func TestStatusFromRunningInstance(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	sessionManager = newTestSessionManager(t, 2)
+	currentState = TimerRunning
+	timeRemaining = 42
+	publishStatus()
+	timeRemaining = 41 // Not published yet, so not served
+
+	if err := startControlSocket(); err != nil {
+		t.Fatalf("Failed to start control socket: %v", err)
+	}
+	defer stopControlSocket()
+
+	status, err := loadStatus()
+	if err != nil {
+		t.Fatalf("loadStatus failed: %v", err)
+	}
+	if status.Source != "instance" || status.TimeRemaining != 42 {
+		t.Errorf("Expected live status from instance, got %+v", status)
+	}
+}
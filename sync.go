@@ -0,0 +1,435 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// SyncSettings shares history, settings and the current cycle with other
+// devices, through a folder kept in sync by something else (Syncthing,
+// Nextcloud...) or a `gomodoro serve-sync` server. They stay with the device:
+// they're never sent, and adopting another device's settings keeps these.
+type SyncSettings struct {
+	Folder          string `json:"folder"`           // Shared folder
+	Server          string `json:"server"`           // Sync server URL (used instead of Folder)
+	Token           string `json:"token"`            // Sync server token (default $GOMODORO_SYNC_TOKEN)
+	IntervalSeconds int    `json:"interval_seconds"` // How often to look for other devices' changes
+}
+
+// DefaultSyncSettings - off until a folder is chosen
+var DefaultSyncSettings = SyncSettings{
+	IntervalSeconds: 60,
+}
+
+// syncSnapshot is one device's latest state in the shared folder
+type syncSnapshot struct {
+	Device     string   `json:"device"`      // Stable random ID
+	DeviceName string   `json:"device_name"` // Hostname, for people
+	State      AppState `json:"state"`       // LastSaved is when the device last changed it
+}
+
+// syncReport is what a pass over the shared folder found
+type syncReport struct {
+	Snapshots       []syncSnapshot // Other devices' states
+	FolderConflicts []string       // Conflict copies made by the sync tool
+}
+
+// SyncSettingsChanged asks the timer goroutine to publish our new settings
+const SyncSettingsChanged = "sync-settings-changed"
+
+// syncChannel hands sync reports to the timer goroutine
+var syncChannel = make(chan syncReport, 1)
+
+// Sync bookkeeping (timer goroutine)
+var (
+	syncRoot      string    // Shared folder for the active profile (empty if off)
+	syncToServer  bool      // Syncing with a server instead
+	syncDeviceID  string    // This device's ID
+	syncChangedAt time.Time // When this device last changed the cycle or settings
+	syncSeenUpTo  time.Time // Newest snapshot from another device already dealt with
+	syncReported  = map[string]bool{}
+)
+
+// getDeviceID returns this device's sync ID, making one up the first time
+func getDeviceID() string {
+	path := filepath.Join(getConfigDir(), "device_id")
+	if data, err := os.ReadFile(path); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data))
+	}
+	id := newRecordID()[:12]
+	if err := writeFileAtomic(path, []byte(id+"\n"), 0644); err != nil {
+		log.Printf("sync: saving device ID: %v", err)
+	}
+	return id
+}
+
+// syncDeviceDir is where a device writes its files; nobody else writes there
+func syncDeviceDir(root, device string) string {
+	return filepath.Join(root, "devices", device)
+}
+
+// setupSync starts syncing through the shared folder or server, if one is set
+func setupSync() {
+	settings := DefaultSettings.Sync
+	if settings.Folder == "" && settings.Server == "" {
+		return
+	}
+	syncDeviceID = getDeviceID()
+	interval := time.Duration(settings.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	// Pick up where we left off: our last published snapshot says when we
+	// last changed anything and how far we'd caught up with the others
+	var lastPublished time.Time
+	if settings.Server != "" {
+		token := settings.Token
+		if token == "" {
+			token = os.Getenv("GOMODORO_SYNC_TOKEN")
+		}
+		syncToServer = true
+		client := NewSyncClient(settings.Server, token, activeProfile, syncDeviceID)
+		clientState := loadSyncClientState(settings.Server)
+		lastPublished = clientState.SnapshotAt
+		go runSyncClient(client, historyStore, clientState, interval)
+	} else {
+		syncRoot = filepath.Join(expandHome(settings.Folder), activeProfile)
+		if err := os.MkdirAll(syncDeviceDir(syncRoot, syncDeviceID), 0755); err != nil {
+			log.Printf("sync: %v", err)
+			return
+		}
+		if own, err := readSyncSnapshot(filepath.Join(syncDeviceDir(syncRoot, syncDeviceID), "state.json")); err == nil {
+			lastPublished = own.State.LastSaved
+		}
+		go runSync(syncRoot, syncDeviceID, historyStore, interval)
+	}
+	if !lastPublished.IsZero() {
+		syncChangedAt, syncSeenUpTo = lastPublished, lastPublished
+	} else if state, err := readAppState(getStateFilePath()); err == nil && state != nil {
+		syncChangedAt = state.LastSaved // Never synced: anything newer elsewhere is a conflict
+	}
+
+	onLifecycleEvent(func(sessionEvent SessionEvent) {
+		noteSyncChange(sessionEvent.Time)
+	})
+}
+
+// runSync merges history and reads the other devices' states every interval
+func runSync(root, device string, store HistoryRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if store != nil {
+			if imported, exported, err := mergeSyncedHistory(store, root, device); err != nil {
+				log.Printf("sync: history: %v", err)
+			} else if imported+exported > 0 {
+				log.Printf("sync: history: %d sessions in, %d out", imported, exported)
+			}
+		}
+		snapshots, err := readSyncSnapshots(root, device)
+		if err != nil {
+			log.Printf("sync: %v", err)
+		}
+		syncChannel <- syncReport{Snapshots: snapshots, FolderConflicts: findFolderConflicts(root)}
+		<-ticker.C
+	}
+}
+
+// mergeSyncedHistory brings in the sessions other devices logged and appends
+// ours (including imports) to our own log. Records are matched by ID, so every
+// device ends up with the same history whatever order things happen in.
+func mergeSyncedHistory(store HistoryRepository, root, device string) (imported, exported int, err error) {
+	local, err := store.Query(HistoryQuery{})
+	if err != nil {
+		return 0, 0, err
+	}
+	have := map[string]bool{}
+	for _, record := range local {
+		have[record.ID] = true
+	}
+
+	logPaths, _ := filepath.Glob(filepath.Join(root, "devices", "*", "history.jsonl"))
+	logged := map[string]bool{}
+	var incoming []HistoryRecord
+	for _, path := range logPaths {
+		records, err := NewHistoryLog(path).Records()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, record := range records {
+			logged[record.ID] = true
+			if !have[record.ID] {
+				have[record.ID] = true
+				incoming = append(incoming, record)
+			}
+		}
+	}
+	if err := store.Append(incoming...); err != nil {
+		return 0, 0, err
+	}
+
+	if err := os.MkdirAll(syncDeviceDir(root, device), 0755); err != nil {
+		return len(incoming), 0, err
+	}
+	ownLog := NewHistoryLog(filepath.Join(syncDeviceDir(root, device), "history.jsonl"))
+	for _, record := range local {
+		if logged[record.ID] {
+			continue
+		}
+		if err := ownLog.Append(record); err != nil {
+			return len(incoming), exported, err
+		}
+		exported++
+	}
+	return len(incoming), exported, nil
+}
+
+// readSyncSnapshot reads one device's state file
+func readSyncSnapshot(path string) (syncSnapshot, error) {
+	// Start from fresh defaults, like readAppState (never the live settings:
+	// decoding into their maps would change them under the timer goroutine)
+	snapshot := syncSnapshot{State: AppState{Settings: settingsToReadOver()}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	if data, err = migrateSnapshotState(data); err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	snapshot.State.Settings.fillMissingMaps()
+	return snapshot, err
+}
+
+// migrateSnapshotState upgrades the state inside a snapshot written by an
+// older GoModoro, the same way the local state file is
+func migrateSnapshotState(data []byte) ([]byte, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if state, ok := raw["state"]; ok {
+		migrated, err := migrateState(state)
+		if err != nil {
+			return nil, err
+		}
+		raw["state"] = migrated
+	}
+	return json.Marshal(raw)
+}
+
+// readSyncSnapshots reads every other device's state, skipping unreadable ones
+func readSyncSnapshots(root, device string) ([]syncSnapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(root, "devices", "*", "state.json"))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []syncSnapshot
+	for _, path := range paths {
+		if filepath.Base(filepath.Dir(path)) == device {
+			continue
+		}
+		snapshot, err := readSyncSnapshot(path)
+		if err != nil {
+			log.Printf("sync: skipping %s: %v", path, err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// findFolderConflicts lists conflict copies the sync tool made of our files
+// (Syncthing's .sync-conflict-, Nextcloud's "conflicted copy")
+func findFolderConflicts(root string) []string {
+	var conflicts []string
+	filepath.WalkDir(filepath.Join(root, "devices"), func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			name := strings.ToLower(entry.Name())
+			if strings.Contains(name, ".sync-conflict-") || strings.Contains(name, "conflicted copy") {
+				conflicts = append(conflicts, path)
+			}
+		}
+		return nil
+	})
+	return conflicts
+}
+
+// newestSnapshot picks the last writer; ties go to the higher device ID so
+// every device picks the same one
+func newestSnapshot(snapshots []syncSnapshot) *syncSnapshot {
+	var newest *syncSnapshot
+	for i := range snapshots {
+		snapshot := &snapshots[i]
+		if newest == nil || snapshot.State.LastSaved.After(newest.State.LastSaved) ||
+			(snapshot.State.LastSaved.Equal(newest.State.LastSaved) && snapshot.Device > newest.Device) {
+			newest = snapshot
+		}
+	}
+	return newest
+}
+
+// sameCycle reports whether two states agree on everything that syncs
+func sameCycle(a, b AppState) bool {
+	a.LastSaved, b.LastSaved = time.Time{}, time.Time{}
+	a.Settings.Sync, b.Settings.Sync = SyncSettings{}, SyncSettings{}
+	aData, _ := json.Marshal(a)
+	bData, _ := json.Marshal(b)
+	return string(aData) == string(bData)
+}
+
+// ownSnapshot is this device's current state, as of its last change
+func ownSnapshot() syncSnapshot {
+	hostname, _ := os.Hostname()
+	state := currentAppState()
+	state.LastSaved = syncChangedAt
+	state.Settings.Sync = SyncSettings{} // Stays here (and keeps the token private)
+	state.Window = nil                   // Screens differ between devices
+	return syncSnapshot{Device: syncDeviceID, DeviceName: hostname, State: state}
+}
+
+// noteSyncChange records a change made on this device and publishes it
+func noteSyncChange(at time.Time) {
+	if syncRoot == "" && !syncToServer {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	syncChangedAt = at
+	if syncToServer {
+		queueSyncSnapshot(ownSnapshot())
+		return
+	}
+	data, err := json.MarshalIndent(ownSnapshot(), "", "  ")
+	if err != nil {
+		log.Printf("sync: %v", err)
+		return
+	}
+	path := filepath.Join(syncDeviceDir(syncRoot, syncDeviceID), "state.json")
+	go func() {
+		os.MkdirAll(filepath.Dir(path), 0755) // The sync tool may have tidied it away
+		if err := writeFileAtomic(path, data, 0644); err != nil {
+			log.Printf("sync: %v", err)
+		}
+	}()
+}
+
+// handleSyncReport applies the newest state from another device, last writer
+// wins. If both sides changed since we last looked, the losing side is kept
+// in the conflicts folder and the user is told (timer goroutine).
+func handleSyncReport(report syncReport) {
+	for _, path := range report.FolderConflicts {
+		if !syncReported[path] {
+			syncReported[path] = true
+			showSyncConflict(fmt.Sprintf("Yer sync tool made a conflict copy:\n%s\nIt's been left alone; have a look and remove it.", path))
+		}
+	}
+
+	remote := newestSnapshot(report.Snapshots)
+	if remote == nil || !remote.State.LastSaved.After(syncSeenUpTo) {
+		return // Nothing new elsewhere
+	}
+	changedHere := syncChangedAt.After(syncSeenUpTo)
+	syncSeenUpTo = remote.State.LastSaved
+
+	local := ownSnapshot()
+	if sameCycle(local.State, remote.State) {
+		return
+	}
+	if !changedHere {
+		adoptSyncedState(remote.State)
+		return
+	}
+
+	// Both sides changed since we last caught up
+	if newestSnapshot([]syncSnapshot{local, *remote}).Device == local.Device {
+		keepSyncConflict(*remote, local) // Ours wins; the others will take it from us
+		return
+	}
+	keepSyncConflict(local, *remote)
+	adoptSyncedState(remote.State)
+}
+
+// adoptSyncedState takes over another device's cycle and settings. A running
+// session comes over paused, with the time it has run since taken off.
+func adoptSyncedState(state AppState) {
+	if ticker != nil {
+		ticker.Stop()
+		ticker = nil
+	}
+	if state.SessionManagerState != nil {
+		sessionManager = state.SessionManagerState
+	}
+	currentState = state.CurrentState
+	timeRemaining = state.TimeRemaining
+	if currentState == TimerRunning {
+		currentState = TimerPaused
+		timeRemaining -= int(time.Since(state.LastSaved).Seconds())
+		if timeRemaining < 1 {
+			timeRemaining = 1
+		}
+	}
+	adoptSharedSettings(state.Settings)
+	currentTask = state.CurrentTask
+	saveAppState()
+	task := currentTask
+	fyne.Do(func() {
+		if taskEntry != nil {
+			taskEntry.SetText(task)
+		}
+		updateUI()
+	})
+}
+
+// adoptSharedSettings takes the cycle settings from another device. The rest
+// (hooks, alert commands, sound and calendar files, idle and lock policy,
+// hotkeys...) belongs to the machine it was set up on.
+func adoptSharedSettings(from GoModoroSettings) {
+	DefaultSettings.Sessions = from.Sessions
+	DefaultSettings.ShortBreak = from.ShortBreak
+	DefaultSettings.LongBreak = from.LongBreak
+	DefaultSettings.LongBreakFrequency = from.LongBreakFrequency
+	DefaultSettings.Surprises = from.Surprises
+	DefaultSettings.SurpriseMinutes = from.SurpriseMinutes
+	DefaultSettings.WarningMinutes = from.WarningMinutes
+}
+
+// keepSyncConflict saves the losing state next to the shared folder's other
+// files and tells the user
+func keepSyncConflict(loser, winner syncSnapshot) {
+	dir := filepath.Join(syncRoot, "conflicts")
+	if syncToServer {
+		dir = filepath.Join(getProfileDir(), "sync_conflicts")
+	}
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", loser.State.LastSaved.UTC().Format("20060102T150405"), loser.Device))
+	if data, err := json.MarshalIndent(loser, "", "  "); err == nil {
+		if err := writeFileAtomic(path, data, 0644); err != nil {
+			log.Printf("sync: saving conflict: %v", err)
+		}
+	}
+	log.Printf("sync: conflict: kept %s's state from %s, saved %s's to %s", winner.DeviceName,
+		winner.State.LastSaved.Format(time.RFC3339), loser.DeviceName, path)
+	showSyncConflict(fmt.Sprintf("Both %s and %s changed the cycle.\nKept the newer one from %s (%s); the other was saved to\n%s",
+		winner.DeviceName, loser.DeviceName, winner.DeviceName, winner.State.LastSaved.Local().Format("15:04:05"), path))
+}
+
+// showSyncConflict tells the user about a sync conflict
+func showSyncConflict(message string) {
+	if myWindow == nil {
+		return
+	}
+	fyne.Do(func() {
+		dialog.ShowInformation("🔀 Sync conflict", message, myWindow)
+	})
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// useTestUI builds the main window's widgets on a headless app
+func useTestUI(t *testing.T) {
+	t.Helper()
+	myApp = test.NewApp()
+	t.Cleanup(func() { myApp = nil })
+	createTimerUI()
+}
+
+// useTestSync points sync at a temp folder as device "desk"
+func useTestSync(t *testing.T, changedAt time.Time) {
+	t.Helper()
+	useTestConfigDir(t)
+	syncRoot, syncDeviceID = t.TempDir(), "desk"
+	syncChangedAt, syncSeenUpTo = changedAt, changedAt
+	t.Cleanup(func() { syncRoot, syncDeviceID = "", "" })
+}
+
+// laptopSnapshot is another device's state with one change from ours
+func laptopSnapshot(at time.Time, remaining int) syncSnapshot {
+	state := currentAppState()
+	state.CurrentState = TimerPaused
+	state.TimeRemaining = remaining
+	state.CurrentTask = "from the laptop"
+	state.LastSaved = at
+	return syncSnapshot{Device: "laptop", DeviceName: "laptop", State: state}
+}
+
+// syncConflicts lists the conflict files kept so far
+func syncConflicts(t *testing.T) []string {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(syncRoot, "conflicts", "*.json"))
+	return files
+}
+
+func TestMergeSyncedHistory(t *testing.T) {
+	root := t.TempDir()
+	records := testHistory()
+	desk := NewHistoryDB(filepath.Join(t.TempDir(), "history.db"))
+	laptop := NewHistoryDB(filepath.Join(t.TempDir(), "history.db"))
+	desk.Append(records[:3]...)
+	laptop.Append(records[2:]...) // c3 got to both somehow (e.g. imported twice)
+
+	for _, step := range []struct {
+		store  HistoryRepository
+		device string
+	}{{desk, "desk"}, {laptop, "laptop"}, {desk, "desk"}} {
+		if _, _, err := mergeSyncedHistory(step.store, root, step.device); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, store := range map[string]*HistoryDB{"desk": desk, "laptop": laptop} {
+		merged, _ := store.Query(HistoryQuery{})
+		if got := recordIDs(merged); got != "a1 b2 c3 d4 e5 " {
+			t.Errorf("%s: expected every record once, got %q", name, got)
+		}
+	}
+	if imported, exported, _ := mergeSyncedHistory(desk, root, "desk"); imported+exported != 0 {
+		t.Errorf("Merging again should change nothing, got %d in %d out", imported, exported)
+	}
+	laptopLog, _ := NewHistoryLog(filepath.Join(root, "devices", "laptop", "history.jsonl")).Records()
+	if got := recordIDs(laptopLog); got != "d4 e5 " {
+		t.Errorf("Devices should only log what others haven't, got %q", got)
+	}
+}
+
+func TestNewestSnapshotIsDeterministic(t *testing.T) {
+	at := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	snapshots := []syncSnapshot{
+		{Device: "b", State: AppState{LastSaved: at}},
+		{Device: "c", State: AppState{LastSaved: at}},
+		{Device: "a", State: AppState{LastSaved: at.Add(-time.Minute)}},
+	}
+	if newest := newestSnapshot(snapshots); newest.Device != "c" {
+		t.Errorf("Ties should go to the higher device ID, got %s", newest.Device)
+	}
+	snapshots[0], snapshots[1] = snapshots[1], snapshots[0]
+	if newest := newestSnapshot(snapshots); newest.Device != "c" {
+		t.Errorf("Order shouldn't matter, got %s", newest.Device)
+	}
+}
+
+func TestSyncAdoptsNewerState(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	sessionManager = newTestSessionManager(t, 2)
+	useTestSync(t, start)
+	useTestUI(t)
+	currentState, timeRemaining, currentTask = TimerReady, 1500, ""
+
+	handleSyncReport(syncReport{Snapshots: []syncSnapshot{laptopSnapshot(start.Add(time.Minute), 900)}})
+
+	if timeRemaining != 900 || currentTask != "from the laptop" || taskEntry.Text != "from the laptop" {
+		t.Errorf("Newer state should be adopted, got %d %q", timeRemaining, currentTask)
+	}
+	if len(syncConflicts(t)) != 0 {
+		t.Error("Only one side changed, so no conflict")
+	}
+
+	// Seen it: the same snapshot again changes nothing
+	timeRemaining = 1200
+	handleSyncReport(syncReport{Snapshots: []syncSnapshot{laptopSnapshot(start.Add(time.Minute), 900)}})
+	if timeRemaining != 1200 {
+		t.Error("An old snapshot shouldn't be adopted twice")
+	}
+}
+
+func TestSyncConflictRemoteWins(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	sessionManager = newTestSessionManager(t, 2)
+	useTestSync(t, start)
+	useTestUI(t)
+	currentState, timeRemaining = TimerReady, 1500
+
+	syncChangedAt = start.Add(time.Minute) // We changed something...
+	remote := laptopSnapshot(start.Add(2*time.Minute), 600)
+	remote.State.CurrentState = TimerRunning // ...and so did the laptop, later, and it's still going
+	handleSyncReport(syncReport{Snapshots: []syncSnapshot{remote}})
+
+	if currentState != TimerPaused || timeRemaining >= 600 || timeRemaining < 1 {
+		t.Errorf("A running session should come over paused with its time run off, got %s %d", currentState, timeRemaining)
+	}
+	conflicts := syncConflicts(t)
+	if len(conflicts) != 1 || !strings.HasSuffix(conflicts[0], "-desk.json") {
+		t.Errorf("Our losing state should be kept, got %v", conflicts)
+	}
+	if lost, err := readSyncSnapshot(conflicts[0]); err != nil || lost.State.TimeRemaining != 1500 {
+		t.Errorf("Conflict file should hold our old state, got %+v %v", lost.State, err)
+	}
+}
+
+func TestSyncConflictLocalWins(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	sessionManager = newTestSessionManager(t, 2)
+	useTestSync(t, start)
+	currentState, timeRemaining = TimerReady, 1500
+
+	syncChangedAt = start.Add(2 * time.Minute)
+	handleSyncReport(syncReport{Snapshots: []syncSnapshot{laptopSnapshot(start.Add(time.Minute), 600)}})
+
+	if timeRemaining != 1500 {
+		t.Errorf("Ours is newer, so it should stay, got %d", timeRemaining)
+	}
+	conflicts := syncConflicts(t)
+	if len(conflicts) != 1 {
+		t.Fatalf("The laptop's losing state should be kept, got %v", conflicts)
+	}
+	if lost, _ := readSyncSnapshot(conflicts[0]); lost.Device != "laptop" {
+		t.Errorf("Expected the laptop's state, got %+v", lost)
+	}
+}
+
+func TestSyncOwnSnapshotAndFolderConflicts(t *testing.T) {
+	sessionManager = newTestSessionManager(t, 2)
+	useTestSync(t, time.Time{})
+	at := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	noteSyncChange(at)
+	ownPath := filepath.Join(syncDeviceDir(syncRoot, "desk"), "state.json")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if own, err := readSyncSnapshot(ownPath); err == nil {
+			if !own.State.LastSaved.Equal(at) || own.Device != "desk" {
+				t.Errorf("Snapshot should carry the change time, got %+v", own)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Own snapshot never written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	copyPath := filepath.Join(syncDeviceDir(syncRoot, "desk"), "state.sync-conflict-20240304-090000-ABCDEFG.json")
+	os.WriteFile(copyPath, []byte("{}"), 0644)
+	if found := findFolderConflicts(syncRoot); len(found) != 1 || found[0] != copyPath {
+		t.Errorf("Expected the Syncthing conflict copy, got %v", found)
+	}
+	if snapshots, _ := readSyncSnapshots(syncRoot, "desk"); len(snapshots) != 0 {
+		t.Errorf("Our own snapshot isn't another device's, got %+v", snapshots)
+	}
+}
+
+func TestReadSyncSnapshotLeavesLiveSettingsAlone(t *testing.T) {
+	sessionManager = newTestSessionManager(t, 2)
+	DefaultSettings.Media.OnSession = map[SessionType]MediaAction{SessionWork: MediaResume}
+	DefaultSettings.Hotkeys.Window = map[string]string{"skip": "S"}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte(`{"device": "laptop", "state": {"settings": {
+		"Media": {"on_session": {"long_break": "pause"}},
+		"Hotkeys": {"window": {"reset": "X"}}}}}`), 0644)
+
+	snapshot, err := readSyncSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(DefaultSettings.Media.OnSession) != 1 || len(DefaultSettings.Hotkeys.Window) != 1 {
+		t.Errorf("Reading another device's snapshot changed our settings: %v %v",
+			DefaultSettings.Media.OnSession, DefaultSettings.Hotkeys.Window)
+	}
+	if len(snapshot.State.Settings.Media.OnSession) != 1 || snapshot.State.Settings.Hotkeys.Window["reset"] != "X" {
+		t.Errorf("Snapshot should have the laptop's own maps, got %+v", snapshot.State.Settings)
+	}
+}
+
+func TestSyncAdoptsOnlyCycleSettings(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	sessionManager = newTestSessionManager(t, 2)
+	useTestSync(t, start)
+	useTestUI(t)
+	DefaultSettings.Hooks = []Hook{{Event: LifecycleSessionStart, Command: "notify-send ours"}}
+
+	laptop := laptopSnapshot(start.Add(time.Minute), 900)
+	laptop.State.Settings.ShortBreak = 7
+	laptop.State.Settings.Hooks = []Hook{{Event: LifecycleSessionStart, Command: "~/bin/laptop-only.sh"}}
+	laptop.State.Settings.AlertCommand = "paplay ~/laptop.ogg"
+	laptop.State.Settings.Idle.Enabled = true
+	handleSyncReport(syncReport{Snapshots: []syncSnapshot{laptop}})
+
+	if DefaultSettings.ShortBreak != 7 {
+		t.Errorf("Cycle settings should come across, got short break %d", DefaultSettings.ShortBreak)
+	}
+	if DefaultSettings.Hooks[0].Command != "notify-send ours" || DefaultSettings.AlertCommand != "" || DefaultSettings.Idle.Enabled {
+		t.Errorf("Device settings shouldn't come across: %+v", DefaultSettings)
+	}
+}
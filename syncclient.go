@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyncClient talks to a `gomodoro serve-sync` server
+type SyncClient struct {
+	server  string // Base URL
+	token   string
+	profile string
+	device  string
+	http    *http.Client
+}
+
+// NewSyncClient syncs profile as device with the server at serverURL
+func NewSyncClient(serverURL, token, profile, device string) *SyncClient {
+	return &SyncClient{
+		server:  strings.TrimRight(serverURL, "/"),
+		token:   token,
+		profile: profile,
+		device:  device,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// profilePath is a path under this profile's part of the protocol
+func (c *SyncClient) profilePath(path string) string {
+	return "/v1/" + url.PathEscape(c.profile) + path
+}
+
+// do sends a request and decodes the JSON reply
+func (c *SyncClient) do(method, path string, body, reply interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	request, err := http.NewRequest(method, c.server+path, payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("sync server: %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(response.Body).Decode(reply)
+}
+
+// Push sends new records and (optionally) this device's snapshot
+func (c *SyncClient) Push(records []HistoryRecord, snapshot *syncSnapshot) (syncPushReply, error) {
+	var reply syncPushReply
+	err := c.do(http.MethodPost, c.profilePath("/push"), syncPush{Device: c.device, Records: records, Snapshot: snapshot}, &reply)
+	return reply, err
+}
+
+// Changes fetches a page of other devices' changes after since
+func (c *SyncClient) Changes(since uint64) (syncChanges, error) {
+	var page syncChanges
+	query := url.Values{"since": {strconv.FormatUint(since, 10)}, "device": {c.device}}
+	err := c.do(http.MethodGet, c.profilePath("/changes?"+query.Encode()), nil, &page)
+	return page, err
+}
+
+// syncClientState is how far this device has got with the server
+type syncClientState struct {
+	Server     string    `json:"server"`      // Cursors only mean something to this server
+	PushCursor uint64    `json:"push_cursor"` // Local history, in the order added
+	PullCursor uint64    `json:"pull_cursor"` // Server change log
+	SnapshotAt time.Time `json:"snapshot_at"` // LastSaved of the last snapshot pushed
+}
+
+// getSyncClientStatePath is where the cursors are kept (per profile)
+func getSyncClientStatePath() string {
+	return filepath.Join(getProfileDir(), "sync_client.json")
+}
+
+// loadSyncClientState reads the cursors, starting over for a different server
+func loadSyncClientState(server string) *syncClientState {
+	state := &syncClientState{}
+	if data, err := os.ReadFile(getSyncClientStatePath()); err == nil {
+		json.Unmarshal(data, state)
+	}
+	if state.Server != server {
+		state = &syncClientState{Server: server}
+	}
+	return state
+}
+
+// save writes the cursors
+func (s *syncClientState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getSyncClientStatePath(), data, 0644)
+}
+
+// syncSnapshotChannel holds this device's latest unsent snapshot
+var syncSnapshotChannel = make(chan syncSnapshot, 1)
+
+// queueSyncSnapshot replaces any unsent snapshot with a newer one
+func queueSyncSnapshot(snapshot syncSnapshot) {
+	select {
+	case <-syncSnapshotChannel:
+	default:
+	}
+	select {
+	case syncSnapshotChannel <- snapshot:
+	default:
+	}
+}
+
+// syncWithServer pushes local history (and snapshot) and pulls the other
+// devices' changes, moving the cursors on as each step succeeds. Records
+// pulled here get pushed back next time; the server ignores ones it has.
+func syncWithServer(client *SyncClient, store HistoryRepository, state *syncClientState, snapshot *syncSnapshot) (syncReport, error) {
+	var report syncReport
+	for {
+		records, next, err := store.Since(state.PushCursor, syncPageSize)
+		if err != nil {
+			return report, err
+		}
+		if len(records) == 0 && snapshot == nil {
+			break
+		}
+		if _, err := client.Push(records, snapshot); err != nil {
+			return report, err
+		}
+		state.PushCursor = next
+		if snapshot != nil {
+			state.SnapshotAt = snapshot.State.LastSaved
+			snapshot = nil
+		}
+		if len(records) < syncPageSize {
+			break
+		}
+	}
+
+	latest := map[string]syncSnapshot{}
+	for {
+		page, err := client.Changes(state.PullCursor)
+		if err != nil {
+			return report, err
+		}
+		var records []HistoryRecord
+		for _, change := range page.Changes {
+			if change.Record != nil {
+				records = append(records, *change.Record)
+			}
+			if change.Snapshot != nil {
+				latest[change.Device] = *change.Snapshot
+			}
+		}
+		if err := store.Append(records...); err != nil {
+			return report, err
+		}
+		state.PullCursor = page.Cursor
+		if !page.More {
+			break
+		}
+	}
+	for _, snapshot := range latest {
+		report.Snapshots = append(report.Snapshots, snapshot)
+	}
+	return report, nil
+}
+
+// runSyncClient syncs every interval, and straight away when something changes here
+func runSyncClient(client *SyncClient, store HistoryRepository, state *syncClientState, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var pending *syncSnapshot
+	for {
+		if pending == nil {
+			select {
+			case snapshot := <-syncSnapshotChannel:
+				pending = &snapshot
+			default:
+			}
+		}
+		report, err := syncWithServer(client, store, state, pending)
+		if err != nil {
+			log.Printf("sync: %v", err) // Try again next time
+		} else {
+			pending = nil
+		}
+		if err := state.save(); err != nil {
+			log.Printf("sync: %v", err)
+		}
+		if len(report.Snapshots) > 0 {
+			syncChannel <- report
+		}
+
+		select {
+		case <-ticker.C:
+		case snapshot := <-syncSnapshotChannel:
+			pending = &snapshot
+		}
+	}
+}
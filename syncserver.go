@@ -0,0 +1,322 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// syncPageSize caps how many changes one pull returns
+const syncPageSize = 500
+
+// syncPush is what a client sends: new history records and, if it changed,
+// its latest snapshot
+type syncPush struct {
+	Device   string          `json:"device"`
+	Records  []HistoryRecord `json:"records,omitempty"`
+	Snapshot *syncSnapshot   `json:"snapshot,omitempty"`
+}
+
+// syncPushReply says how much of a push was new
+type syncPushReply struct {
+	Accepted int    `json:"accepted"` // Records the server didn't have yet
+	Cursor   uint64 `json:"cursor"`   // Latest change on the server
+}
+
+// syncChange is one entry in the server's change log
+type syncChange struct {
+	Seq      uint64         `json:"seq"`
+	Device   string         `json:"device"`
+	Record   *HistoryRecord `json:"record,omitempty"`
+	Snapshot *syncSnapshot  `json:"snapshot,omitempty"`
+}
+
+// syncChanges is a page of changes after a cursor
+type syncChanges struct {
+	Changes []syncChange `json:"changes"`
+	Cursor  uint64       `json:"cursor"` // Pass back as ?since= for the next page
+	More    bool         `json:"more"`
+}
+
+// Buckets inside each profile's bucket on the server (which sits in the
+// bucket of the account, i.e. token, it belongs to)
+var (
+	syncLogBucket       = []byte("log")       // seq -> syncChange JSON
+	syncIDsBucket       = []byte("ids")       // record ID -> seq
+	syncSnapshotsBucket = []byte("snapshots") // device -> seq of its latest snapshot
+)
+
+// SyncServer keeps a change log per account and profile for clients to push
+// to and pull from. Each token is one account: its devices share their data,
+// and no other token can see it.
+type SyncServer struct {
+	db       *bolt.DB
+	tokens   []string
+	presence serverTeams // Teammates' statuses, in memory only
+}
+
+// NewSyncServer serves the database at path to clients with one of the tokens
+func NewSyncServer(path string, tokens []string) (*SyncServer, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("no tokens: refusing to run an open sync server")
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &SyncServer{db: db, tokens: tokens}, nil
+}
+
+// Close closes the server's database
+func (s *SyncServer) Close() error {
+	return s.db.Close()
+}
+
+// Handler routes the sync protocol
+func (s *SyncServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/{profile}/push", s.authorized(s.handlePush))
+	mux.HandleFunc("GET /v1/{profile}/changes", s.authorized(s.handleChanges))
+	mux.HandleFunc("POST /v1/team/{team}/presence", s.authorized(s.handleSetPresence))
+	mux.HandleFunc("GET /v1/team/{team}/presence", s.authorized(s.handlePresence))
+	return mux
+}
+
+// authorized checks the bearer token and the profile (or team) name before handling
+func (s *SyncServer) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := requestToken(r)
+		if !ok || !s.validToken(token) {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		name := r.PathValue("profile")
+		if name == "" {
+			name = r.PathValue("team")
+		}
+		if validateProfileName(name) != nil {
+			http.Error(w, "bad profile", http.StatusBadRequest)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// requestToken is the request's bearer token
+func requestToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// accountBucketName is where a token's profiles live. The token is hashed so
+// the database doesn't hold it.
+func accountBucketName(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return []byte("account:" + hex.EncodeToString(sum[:16]))
+}
+
+// validToken compares in constant time so the token can't be guessed bit by bit
+func (s *SyncServer) validToken(token string) bool {
+	valid := false
+	for _, want := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// profileBucket returns an account's profile bucket, creating it and its buckets
+func profileBucket(tx *bolt.Tx, token, profile string) (*bolt.Bucket, error) {
+	account, err := tx.CreateBucketIfNotExists(accountBucketName(token))
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := account.CreateBucketIfNotExists([]byte("profile:" + profile))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range [][]byte{syncLogBucket, syncIDsBucket, syncSnapshotsBucket} {
+		if _, err := bucket.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
+
+// appendChange adds a change to the log under the next sequence number
+func appendChange(bucket *bolt.Bucket, change syncChange) (uint64, error) {
+	logBucket := bucket.Bucket(syncLogBucket)
+	seq, err := logBucket.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+	change.Seq = seq
+	data, err := json.Marshal(change)
+	if err != nil {
+		return 0, err
+	}
+	return seq, logBucket.Put(seqKey(seq), data)
+}
+
+// handlePush stores new records and replaces the device's snapshot
+func (s *SyncServer) handlePush(w http.ResponseWriter, r *http.Request) {
+	var push syncPush
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<20)).Decode(&push); err != nil || push.Device == "" {
+		http.Error(w, "bad push", http.StatusBadRequest)
+		return
+	}
+
+	token, _ := requestToken(r)
+	var reply syncPushReply
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := profileBucket(tx, token, r.PathValue("profile"))
+		if err != nil {
+			return err
+		}
+		ids := bucket.Bucket(syncIDsBucket)
+		for i := range push.Records {
+			record := push.Records[i]
+			if record.ID == "" || ids.Get([]byte(record.ID)) != nil {
+				continue // Already have it (possibly from another device)
+			}
+			seq, err := appendChange(bucket, syncChange{Device: push.Device, Record: &record})
+			if err != nil {
+				return err
+			}
+			if err := ids.Put([]byte(record.ID), seqKey(seq)); err != nil {
+				return err
+			}
+			reply.Accepted++
+		}
+
+		if push.Snapshot != nil {
+			push.Snapshot.Device = push.Device
+			// Only the latest snapshot matters, so the old one leaves the log
+			snapshots := bucket.Bucket(syncSnapshotsBucket)
+			if old := snapshots.Get([]byte(push.Device)); old != nil {
+				if err := bucket.Bucket(syncLogBucket).Delete(old); err != nil {
+					return err
+				}
+			}
+			seq, err := appendChange(bucket, syncChange{Device: push.Device, Snapshot: push.Snapshot})
+			if err != nil {
+				return err
+			}
+			if err := snapshots.Put([]byte(push.Device), seqKey(seq)); err != nil {
+				return err
+			}
+		}
+		reply.Cursor = bucket.Bucket(syncLogBucket).Sequence()
+		return nil
+	})
+	if err != nil {
+		log.Printf("serve-sync: push: %v", err)
+		http.Error(w, "storage error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, reply)
+}
+
+// handleChanges returns other devices' changes after ?since=
+func (s *SyncServer) handleChanges(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	if err != nil && r.URL.Query().Get("since") != "" {
+		http.Error(w, "bad cursor", http.StatusBadRequest)
+		return
+	}
+	device := r.URL.Query().Get("device")
+	token, _ := requestToken(r)
+
+	page := syncChanges{Changes: []syncChange{}, Cursor: since}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		account := tx.Bucket(accountBucketName(token))
+		if account == nil {
+			return nil // Nobody with this token has pushed yet
+		}
+		bucket := account.Bucket([]byte("profile:" + r.PathValue("profile")))
+		if bucket == nil {
+			return nil // Nobody has pushed yet
+		}
+		cursor := bucket.Bucket(syncLogBucket).Cursor()
+		for key, data := cursor.Seek(seqKey(since + 1)); key != nil; key, data = cursor.Next() {
+			if len(page.Changes) == syncPageSize {
+				page.More = true
+				break
+			}
+			var change syncChange
+			if err := json.Unmarshal(data, &change); err != nil {
+				return err
+			}
+			page.Cursor = change.Seq
+			if change.Device != device {
+				page.Changes = append(page.Changes, change)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("serve-sync: changes: %v", err)
+		http.Error(w, "storage error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, page)
+}
+
+// writeJSON sends value as the response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+// runServeSyncCommand runs the sync server until killed
+func runServeSyncCommand(args []string) int {
+	flags := flag.NewFlagSet("serve-sync", flag.ContinueOnError)
+	listen := flags.String("listen", ":8765", "address to listen on")
+	dataDir := flags.String("data", filepath.Join(getConfigDir(), "sync-server"), "directory for the server's database")
+	tokens := flags.String("token", os.Getenv("GOMODORO_SYNC_TOKEN"), "comma-separated tokens clients must send, one per person (default $GOMODORO_SYNC_TOKEN)")
+	certFile := flags.String("tls-cert", "", "TLS certificate (serve HTTPS)")
+	keyFile := flags.String("tls-key", "", "TLS key")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var tokenList []string
+	for _, token := range strings.Split(*tokens, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokenList = append(tokenList, token)
+		}
+	}
+	if err := os.MkdirAll(*dataDir, 0700); err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro serve-sync:", err)
+		return 1
+	}
+	server, err := NewSyncServer(filepath.Join(*dataDir, "sync.db"), tokenList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomodoro serve-sync:", err)
+		return 1
+	}
+	defer server.Close()
+
+	httpServer := &http.Server{Addr: *listen, Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	log.Printf("serve-sync: listening on %s", *listen)
+	if *certFile != "" {
+		err = httpServer.ListenAndServeTLS(*certFile, *keyFile)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	fmt.Fprintln(os.Stderr, "gomodoro serve-sync:", err)
+	return 1
+}
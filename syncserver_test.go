@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestSyncServer runs a sync server on loopback
+func startTestSyncServer(t *testing.T) string {
+	t.Helper()
+	server, err := NewSyncServer(filepath.Join(t.TempDir(), "sync.db"), []string{"old-token", "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Close()
+	})
+	return httpServer.URL
+}
+
+// testSyncDevice is one client with its own history
+type testSyncDevice struct {
+	client *SyncClient
+	store  *HistoryDB
+	state  *syncClientState
+}
+
+func newTestSyncDevice(t *testing.T, url, device string) *testSyncDevice {
+	t.Helper()
+	return &testSyncDevice{
+		client: NewSyncClient(url+"/", "s3cret", "work", device),
+		store:  NewHistoryDB(filepath.Join(t.TempDir(), "history.db")),
+		state:  &syncClientState{Server: url},
+	}
+}
+
+// sync runs one round for the device
+func (d *testSyncDevice) sync(t *testing.T, snapshot *syncSnapshot) syncReport {
+	t.Helper()
+	report, err := syncWithServer(d.client, d.store, d.state, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestSyncServerEndToEnd(t *testing.T) {
+	url := startTestSyncServer(t)
+	desk := newTestSyncDevice(t, url, "desk")
+	laptop := newTestSyncDevice(t, url, "laptop")
+	records := testHistory()
+
+	desk.store.Append(records[:3]...)
+	deskState := AppState{TimeRemaining: 900, CurrentTask: "from the desk", LastSaved: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)}
+	desk.sync(t, &syncSnapshot{Device: "desk", DeviceName: "desk", State: deskState})
+	if desk.state.PushCursor != 3 || !desk.state.SnapshotAt.Equal(deskState.LastSaved) {
+		t.Errorf("Push cursor should move on, got %+v", desk.state)
+	}
+
+	laptop.store.Append(records[3:]...)
+	report := laptop.sync(t, &syncSnapshot{Device: "laptop", State: AppState{LastSaved: deskState.LastSaved}})
+	if len(report.Snapshots) != 1 || report.Snapshots[0].State.CurrentTask != "from the desk" {
+		t.Errorf("Laptop should get the desk's snapshot, got %+v", report.Snapshots)
+	}
+	if got, _ := laptop.store.Query(HistoryQuery{}); recordIDs(got) != "a1 b2 c3 d4 e5 " {
+		t.Errorf("Laptop should have everything, got %q", recordIDs(got))
+	}
+
+	// Incremental: nothing new means nothing comes back
+	if report := laptop.sync(t, nil); len(report.Snapshots) != 0 {
+		t.Errorf("Nothing should be pulled twice, got %+v", report)
+	}
+
+	if report := desk.sync(t, nil); len(report.Snapshots) != 1 || report.Snapshots[0].Device != "laptop" {
+		t.Errorf("Desk should get the laptop's snapshot, got %+v", report.Snapshots)
+	}
+	if got, _ := desk.store.Query(HistoryQuery{}); recordIDs(got) != "a1 b2 c3 d4 e5 " {
+		t.Errorf("Desk should have the laptop's sessions, got %q", recordIDs(got))
+	}
+
+	// A new snapshot replaces the old one on the server
+	deskState.LastSaved = deskState.LastSaved.Add(time.Minute)
+	deskState.CurrentTask = "newer"
+	desk.sync(t, &syncSnapshot{Device: "desk", State: deskState})
+	fresh := newTestSyncDevice(t, url, "tablet")
+	report = fresh.sync(t, nil)
+	if len(report.Snapshots) != 2 {
+		t.Fatalf("Expected one snapshot per device, got %+v", report.Snapshots)
+	}
+	for _, snapshot := range report.Snapshots {
+		if snapshot.Device == "desk" && snapshot.State.CurrentTask != "newer" {
+			t.Errorf("Expected the desk's latest snapshot, got %+v", snapshot.State)
+		}
+	}
+}
+
+func TestSyncServerPagesChanges(t *testing.T) {
+	url := startTestSyncServer(t)
+	desk := newTestSyncDevice(t, url, "desk")
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	var many []HistoryRecord
+	for i := 0; i < syncPageSize+20; i++ {
+		many = append(many, HistoryRecord{ID: "r" + string(rune('a'+i%26)) + strings.Repeat("x", i/26),
+			Kind: HistoryCompleted, SessionType: SessionWork, Start: start.Add(time.Duration(i) * time.Minute)})
+	}
+	desk.store.Append(many...)
+	desk.sync(t, nil)
+
+	laptop := newTestSyncDevice(t, url, "laptop")
+	laptop.sync(t, nil)
+	if got, _ := laptop.store.Query(HistoryQuery{}); len(got) != len(many) {
+		t.Errorf("Expected all %d records over several pages, got %d", len(many), len(got))
+	}
+	if laptop.state.PullCursor != uint64(len(many)) {
+		t.Errorf("Pull cursor should end at the last change, got %d", laptop.state.PullCursor)
+	}
+}
+
+func TestSyncServerNeedsToken(t *testing.T) {
+	url := startTestSyncServer(t)
+	for _, token := range []string{"", "wrong"} {
+		client := NewSyncClient(url, token, "work", "desk")
+		if _, err := client.Changes(0); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("Token %q: expected 401, got %v", token, err)
+		}
+	}
+	if _, err := NewSyncClient(url, "old-token", "work", "desk").Changes(0); err != nil {
+		t.Errorf("Any configured token should work, got %v", err)
+	}
+
+	response, _ := http.Get(url + "/v1/..%2Fetc/changes")
+	if response.StatusCode != http.StatusUnauthorized && response.StatusCode != http.StatusBadRequest &&
+		response.StatusCode != http.StatusNotFound {
+		t.Errorf("Unexpected status %d", response.StatusCode)
+	}
+	if _, err := NewSyncServer(filepath.Join(t.TempDir(), "open.db"), nil); err == nil {
+		t.Error("A server without tokens should refuse to start")
+	}
+}
+
+func TestSyncClientStateResetsForNewServer(t *testing.T) {
+	useTestConfigDir(t)
+	state := loadSyncClientState("https://one.example")
+	state.PushCursor, state.PullCursor = 12, 34
+	if err := state.save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadSyncClientState("https://one.example"); got.PushCursor != 12 || got.PullCursor != 34 {
+		t.Errorf("Cursors should be kept, got %+v", got)
+	}
+	if got := loadSyncClientState("https://two.example"); got.PushCursor != 0 || got.PullCursor != 0 {
+		t.Errorf("Another server should start from scratch, got %+v", got)
+	}
+}
+
+func TestSyncServerKeepsTokensApart(t *testing.T) {
+	url := startTestSyncServer(t)
+	ana := newTestSyncDevice(t, url, "desk")
+	ben := newTestSyncDevice(t, url, "laptop")
+	ben.client = NewSyncClient(url+"/", "old-token", "work", "laptop") // Same profile, other person
+	records := testHistory()
+
+	ana.store.Append(records[:2]...)
+	ana.sync(t, &syncSnapshot{Device: "desk", State: AppState{CurrentTask: "ana's", LastSaved: time.Now()}})
+	ben.store.Append(records[2:]...)
+	report := ben.sync(t, &syncSnapshot{Device: "laptop", State: AppState{CurrentTask: "ben's", LastSaved: time.Now()}})
+
+	if len(report.Snapshots) != 0 {
+		t.Errorf("Ben shouldn't get Ana's timer, got %+v", report.Snapshots)
+	}
+	if got, _ := ben.store.Query(HistoryQuery{}); recordIDs(got) != "c3 d4 e5 " {
+		t.Errorf("Ben should only have Ben's own history, got %q", recordIDs(got))
+	}
+	if report := ana.sync(t, nil); len(report.Snapshots) != 0 {
+		t.Errorf("Ana shouldn't get Ben's timer, got %+v", report.Snapshots)
+	}
+	if got, _ := ana.store.Query(HistoryQuery{}); recordIDs(got) != "a1 b2 " {
+		t.Errorf("Ana should only have Ana's own history, got %q", recordIDs(got))
+	}
+}
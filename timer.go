@@ -20,9 +20,22 @@ func updateUI() {
 // startTimer begins a new timer session
 func startTimer() {
 	if currentState == TimerReady || currentState == TimerPaused {
+		resumed := currentState == TimerPaused
+		if !resumed {
+			planAroundMeetings() // May shorten this session or swap in a break
+		}
 		currentState = TimerRunning
 		// Create a new ticker that fires every second
 		ticker = time.NewTicker(1 * time.Second)
+
+		// Work sessions remember what was being worked on
+		if current := sessionManager.GetCurrentSession(); current != nil && current.Type == SessionWork {
+			current.Task = currentTask
+		}
+
+		sessionEvent := newSessionEvent(LifecycleSessionStart)
+		sessionEvent.Resumed = resumed
+		publishSessionEvent(sessionEvent)
 		updateUI()
 	}
 }
@@ -34,6 +47,7 @@ func pauseTimer() {
 		if ticker != nil {
 			ticker.Stop() // Stop the countdown
 		}
+		emitLifecycleEvent(LifecycleSessionPause)
 		updateUI()
 	}
 }
@@ -43,6 +57,7 @@ func resetTimer() {
 	currentState = TimerReady
 	if current := sessionManager.GetCurrentSession(); current != nil {
 		timeRemaining = current.Duration
+		current.WarningsFired = nil // Restarting the session re-arms its warnings
 	} else {
 		timeRemaining = 25 * 60 // Default fallback
 	}
@@ -61,37 +76,66 @@ func nextSession() {
 		if current := sessionManager.GetCurrentSession(); current != nil {
 			timeRemaining = current.Duration
 		}
+		emitLifecycleEvent(LifecycleSessionChange)
 		updateUI()
 	} else {
 		// All sessions complete - start new cycle
+		emitLifecycleEvent(LifecycleCycleComplete)
 		sessionManager = NewSessionManager()
 		currentState = TimerReady
 		if current := sessionManager.GetCurrentSession(); current != nil {
 			timeRemaining = current.Duration
 		}
+		emitLifecycleEvent(LifecycleSessionChange)
 		updateUI()
 	}
 }
 
 // skipSession skips the current session
 func skipSession() {
+	emitLifecycleEvent(LifecycleSessionSkip)
 	if sessionManager.SkipCurrentSession() {
 		currentState = TimerReady
 		if current := sessionManager.GetCurrentSession(); current != nil {
 			timeRemaining = current.Duration
 		}
+		emitLifecycleEvent(LifecycleSessionChange)
 		updateUI()
 	} else {
 		// No more sessions - start new cycle
+		emitLifecycleEvent(LifecycleCycleComplete)
 		sessionManager = NewSessionManager()
 		currentState = TimerReady
 		if current := sessionManager.GetCurrentSession(); current != nil {
 			timeRemaining = current.Duration
 		}
+		emitLifecycleEvent(LifecycleSessionChange)
 		updateUI()
 	}
 }
 
+// finishSession ends the running session when its time is up
+func finishSession() {
+	// Timer finished - UNLEASH THE KRAKEN OF NOTIFICATIONS!
+	currentState = TimerFinished
+	timeRemaining = 0
+	if ticker != nil {
+		ticker.Stop()
+	}
+	emitLifecycleEvent(LifecycleSessionFinish)
+	// Fire whichever alerts are routed for this session
+	triggerSessionAlerts()
+}
+
+// snoozeSession gives a finished session a few more minutes
+func snoozeSession() {
+	if currentState == TimerFinished {
+		timeRemaining = snoozeMinutes * 60
+		currentState = TimerPaused
+		startTimer()
+	}
+}
+
 // timerGoroutine runs in the background and handles the countdown
 // This is where the Go concurrency magic happens!
 func timerGoroutine() {
@@ -99,6 +143,12 @@ func timerGoroutine() {
 		select {
 		// Listen for control commands from UI
 		case command := <-controlChannel:
+			// Shortcuts do whatever their button would right now
+			command = hotkeyCommand(command)
+			// In someone else's room the buttons steer the whole room
+			if forwardRoomCommand(command) {
+				continue
+			}
 			switch command {
 			case "start":
 				startTimer()
@@ -110,7 +160,62 @@ func timerGoroutine() {
 				nextSession()
 			case "skip":
 				skipSession()
+			case ActionStartNext:
+				// From a notification: move on and get going straight away
+				if currentState == TimerFinished {
+					nextSession()
+					startTimer()
+				}
+			case ActionSnooze:
+				snoozeSession()
+			case ActionSkipNext:
+				// From a notification: move on, then skip what comes next
+				if currentState == TimerFinished {
+					nextSession()
+					skipSession()
+				}
+			case IdleKeep:
+				idleAwaySeconds = 0
+			case IdleDiscard:
+				discardIdleTime()
+			case RoomLeave:
+				if roomJoined != nil {
+					leaveRoom(false)
+				}
+				closeRoom()
+			case SyncSettingsChanged:
+				noteSyncChange(time.Now())
+			case PresenceShare:
+				// The team panel changed what we share; connect if newly turned on
+				setupPresence()
+			default:
+				// Hosting or joining a room, from the rooms dialog
+				handleRoomCommand(command)
 			}
+			// Guests of a room we host follow along, and teammates see the change
+			shareRoomState()
+			sharePresence()
+
+		// Listen for the user wandering off and coming back
+		case report := <-idleChannel:
+			handleIdleReport(report)
+			sharePresence() // Away shows as idle
+
+		// Listen for the screen locking and the machine sleeping
+		case event := <-powerChannel:
+			handlePowerEvent(event)
+
+		// Listen for other devices' changes in the sync folder
+		case report := <-syncChannel:
+			handleSyncReport(report)
+
+		// Listen for the host of the room we're in
+		case event := <-roomChannel:
+			handleRoomEvent(event)
+
+		// Listen for teammates' statuses
+		case teammates := <-presenceChannel:
+			updateTeamPanel(teammates)
 
 		// Listen for ticker events (every second when running)
 		case <-func() <-chan time.Time {
@@ -122,13 +227,10 @@ func timerGoroutine() {
 		}():
 			if currentState == TimerRunning {
 				timeRemaining--
+				playTimerSounds()
+				checkSessionWarnings()
 				if timeRemaining <= 0 {
-					// Timer finished - UNLEASH THE KRAKEN OF NOTIFICATIONS!
-					currentState = TimerFinished
-					timeRemaining = 0
-					ticker.Stop()
-					// Fire all the alerts!
-					triggerAllAlerts()
+					finishSession()
 				}
 				updateUI()
 			}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Tray state (nil when the platform has no system tray)
+var (
+	trayApp            desktop.App
+	trayMenu           *fyne.Menu
+	trayTimeItem       *fyne.MenuItem
+	trayStartPauseItem *fyne.MenuItem
+	trayIconKey        string // Last rendered icon, so we only redraw when it changes
+)
+
+// setupSystemTray adds the tray icon and its control menu if the desktop supports it
+func setupSystemTray() {
+	desk, ok := myApp.(desktop.App)
+	if !ok {
+		return // Mobile or headless - no tray
+	}
+	trayApp = desk
+
+	// Disabled item used as a live countdown header
+	trayTimeItem = fyne.NewMenuItem("", nil)
+	trayTimeItem.Disabled = true
+
+	trayStartPauseItem = fyne.NewMenuItem("Start", func() {
+		switch currentState {
+		case TimerReady, TimerPaused:
+			controlChannel <- "start"
+		case TimerRunning:
+			controlChannel <- "pause"
+		case TimerFinished:
+			controlChannel <- "next"
+		}
+	})
+
+	showItem := fyne.NewMenuItem("Show Window", showFullWindow)
+
+	// Our own quit so state is saved like it is on window close
+	quitItem := fyne.NewMenuItem("Quit", quitApp)
+	quitItem.IsQuit = true
+
+	trayMenu = fyne.NewMenu("GoModoro",
+		trayTimeItem,
+		fyne.NewMenuItemSeparator(),
+		trayStartPauseItem,
+		fyne.NewMenuItem("Skip", func() { controlChannel <- "skip" }),
+		fyne.NewMenuItem("Reset", func() { controlChannel <- "reset" }),
+		fyne.NewMenuItem("Next", func() { controlChannel <- "next" }),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Settings", showSettingsWindow),
+		showItem,
+		quitItem,
+	)
+	desk.SetSystemTrayMenu(trayMenu)
+	updateTray()
+}
+
+// updateTray refreshes the tray icon and menu labels from the timer state
+func updateTray() {
+	if trayApp == nil {
+		return
+	}
+	status := currentStatus()
+
+	// Menu labels
+	if status.SessionType != "" {
+		trayTimeItem.Label = fmt.Sprintf("%s – %s", status.SessionLabel, formatTime(status.TimeRemaining))
+	} else {
+		trayTimeItem.Label = "🎉 All Sessions Complete!"
+	}
+	switch status.State {
+	case TimerRunning:
+		trayStartPauseItem.Label = "Pause"
+	case TimerPaused:
+		trayStartPauseItem.Label = "Resume"
+	case TimerFinished:
+		trayStartPauseItem.Label = "Next Session"
+	default:
+		trayStartPauseItem.Label = "Start"
+	}
+	trayMenu.Refresh()
+
+	// Only redraw the icon when the minute, session or state changes
+	minutes := (status.TimeRemaining + 59) / 60
+	key := fmt.Sprintf("%d|%s|%s", minutes, status.SessionType, status.State)
+	if key == trayIconKey {
+		return
+	}
+	trayIconKey = key
+
+	iconData, err := renderTrayIcon(minutes, statusColor(status))
+	if err != nil {
+		return
+	}
+	trayApp.SetSystemTrayIcon(fyne.NewStaticResource("gomodoro-tray.png", iconData))
+}
+
+// renderTrayIcon draws the remaining minutes on a coloured disc as a PNG
+func renderTrayIcon(minutes int, hexColor string) ([]byte, error) {
+	const size = 32
+	if minutes > 99 {
+		minutes = 99
+	}
+	text := strconv.Itoa(minutes)
+
+	// Disc in the session colour
+	bg := parseHexColor(hexColor)
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	center := float64(size-1) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)-center, float64(y)-center
+			if dx*dx+dy*dy <= center*center {
+				img.Set(x, y, bg)
+			}
+		}
+	}
+
+	// The bitmap font is tiny, so draw the digits small and scale them up 2x
+	face := basicfont.Face7x13
+	textWidth := font.MeasureString(face, text).Ceil()
+	glyphs := image.NewRGBA(image.Rect(0, 0, textWidth, face.Height))
+	drawer := &font.Drawer{
+		Dst:  glyphs,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	drawer.DrawString(text)
+
+	offsetX := (size - textWidth*2) / 2
+	offsetY := (size - face.Height*2) / 2
+	for y := 0; y < face.Height*2; y++ {
+		for x := 0; x < textWidth*2; x++ {
+			if _, _, _, a := glyphs.At(x/2, y/2).RGBA(); a > 0 {
+				img.Set(offsetX+x, offsetY+y, color.White)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseHexColor turns "#rrggbb" into a colour (grey if malformed)
+func parseHexColor(hex string) color.RGBA {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{0x88, 0x88, 0x88, 0xff}
+	}
+	return color.RGBA{r, g, b, 0xff}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestRenderTrayIcon(t *testing.T) {
+	for _, minutes := range []int{0, 5, 25, 150} {
+		data, err := renderTrayIcon(minutes, "#e06c75")
+		if err != nil {
+			t.Fatalf("renderTrayIcon(%d) failed: %v", minutes, err)
+		}
+
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("renderTrayIcon(%d) is not a PNG: %v", minutes, err)
+		}
+		if img.Bounds().Dx() != 32 || img.Bounds().Dy() != 32 {
+			t.Errorf("Expected 32x32 icon, got %v", img.Bounds())
+		}
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	c := parseHexColor("#98c379")
+	if c.R != 0x98 || c.G != 0xc3 || c.B != 0x79 {
+		t.Errorf("Unexpected colour %v", c)
+	}
+
+	if grey := parseHexColor("nope"); grey.R != 0x88 {
+		t.Errorf("Malformed colour should fall back to grey, got %v", grey)
+	}
+}
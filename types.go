@@ -1,6 +1,8 @@
 package main
 
 import (
+	"maps"
+	"slices"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -21,6 +23,7 @@ var (
 	timeRemaining = 30 // Will be set by session manager
 	currentState  = TimerReady
 	ticker        *time.Ticker // Go's built-in timer that fires every interval
+	currentTask   string       // Label stamped on work sessions when they start
 
 	// UI references
 	timeDisplay         *widget.Label
@@ -29,6 +32,7 @@ var (
 	completedList       *widget.Label
 	remainingLabel      *widget.Label
 	remainingList       *widget.Label
+	taskEntry           *widget.Entry
 	startPauseBtn       *widget.Button
 	resetBtn            *widget.Button
 	skipBtn             *widget.Button
@@ -49,14 +53,71 @@ type GoModoroSettings struct {
 	LongBreakFrequency   int // 1=/2, 2=/3, 3=/4, 4=/5 (default: 1)
 	Surprises            int // Max surprise tasks per cycle (default: 3)
 	SurpriseMinutes      int // Duration of surprise tasks (default: 2)
+	MinimizeToTray       bool // Closing the window hides it to the tray (default: false)
+	AlertRoutes          []AlertRoute // Which notifiers fire for which event (default: all three on finish)
+	AlertCommand         string       // Shell command run by the "command" notifier
+	Sounds               SoundSettings // Finish, warning and ticking sounds
+	WarningMinutes       map[SessionType][]int // Heads-up alerts before the end, per session type
+	Hooks                []Hook                // Shell commands run on session lifecycle events
+	Webhooks             []Webhook             // URLs that get session events POSTed to them
+	Media                MediaSettings         // Pause/resume MPRIS media players between sessions
+	Idle                 IdleSettings          // Auto-pause work sessions when nobody's at the keyboard
+	Lock                 LockSettings          // What the timer does on screen lock and suspend
+	Calendar             CalendarSettings      // ICS calendars whose meetings the cycle works around
+	Sync                 SyncSettings          // Shared folder for history, settings and the cycle across devices
+	Rooms                RoomSettings          // Team focus rooms shared over the LAN
+	Presence             PresenceSettings      // Status shared with teammates, and what of it
+	Hotkeys              HotkeySettings        // Keys for the buttons, in the window and system-wide
 }
 
 // Default settings
-var DefaultSettings = GoModoroSettings{
-	Sessions:           6,
-	ShortBreak:         4,
-	LongBreak:          30,
-	LongBreakFrequency: 1,  // One long break in the middle
-	Surprises:          3,
-	SurpriseMinutes:    2,
-}
\ No newline at end of file
+var DefaultSettings = defaultSettings()
+
+// defaultSettings returns a fresh copy of the built-in settings, with maps
+// and slices of its own so nothing decoded over it changes the defaults
+func defaultSettings() GoModoroSettings {
+	settings := settingsToReadOver()
+	settings.fillMissingMaps()
+	return settings
+}
+
+// settingsToReadOver is what saved settings are decoded over: the defaults,
+// minus the maps. A saved map then replaces the default one (so keys can be
+// removed) instead of being merged into it; fillMissingMaps puts the
+// defaults back for maps the file didn't have.
+func settingsToReadOver() GoModoroSettings {
+	settings := GoModoroSettings{
+		Sessions:           6,
+		ShortBreak:         4,
+		LongBreak:          30,
+		LongBreakFrequency: 1,  // One long break in the middle
+		Surprises:          3,
+		SurpriseMinutes:    2,
+		Sounds:             DefaultSoundSettings,
+		Media:              DefaultMediaSettings,
+		Idle:               DefaultIdleSettings,
+		Lock:               DefaultLockSettings,
+		Calendar:           DefaultCalendarSettings,
+		Sync:               DefaultSyncSettings,
+		Rooms:              DefaultRoomSettings,
+		Presence:           DefaultPresenceSettings,
+		Hotkeys:            DefaultHotkeySettings,
+	}
+	settings.Sounds.TickingSessions = slices.Clone(DefaultSoundSettings.TickingSessions)
+	settings.Media.OnSession = nil
+	settings.Hotkeys.Window, settings.Hotkeys.Global = nil, nil
+	return settings
+}
+
+// fillMissingMaps gives setting maps that weren't saved their defaults
+func (s *GoModoroSettings) fillMissingMaps() {
+	if s.Media.OnSession == nil {
+		s.Media.OnSession = maps.Clone(DefaultMediaSettings.OnSession)
+	}
+	if s.Hotkeys.Window == nil {
+		s.Hotkeys.Window = maps.Clone(DefaultHotkeySettings.Window)
+	}
+	if s.Hotkeys.Global == nil {
+		s.Hotkeys.Global = maps.Clone(DefaultHotkeySettings.Global)
+	}
+}
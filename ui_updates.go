@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // updateSessionDisplay updates the session progress lists
@@ -51,12 +52,25 @@ func updateSessionDisplay() {
 		for i := 0; i < limit; i++ {
 			remainingText = append(remainingText, "• "+remaining[i].GetSessionLabel())
 		}
-		remainingList.SetText(strings.Join(remainingText, "\n"))
+		remainingList.SetText(strings.Join(remainingText, "\n") + meetingLines())
 	} else {
-		remainingList.SetText("Last one!")
+		remainingList.SetText("Last one!" + meetingLines())
 	}
 }
 
+// meetingLines lists the next couple of calendar meetings under the remaining sessions
+func meetingLines() string {
+	meetings := upcomingMeetings(time.Now())
+	if len(meetings) > 2 {
+		meetings = meetings[:2]
+	}
+	text := ""
+	for _, meeting := range meetings {
+		text += "\n" + formatMeeting(meeting)
+	}
+	return text
+}
+
 // updateUIWithSession updates UI based on current timer state and session
 func updateUIWithSession() {
 	timeDisplay.SetText(formatTime(timeRemaining))
@@ -97,4 +111,9 @@ func updateUIWithSession() {
 			}
 		}
 	}
+
+	// Keep the tray countdown, the mini window and `gomodoro status` in step
+	publishStatus()
+	updateTray()
+	updateMiniWindow()
 }
\ No newline at end of file
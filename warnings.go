@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// warningThresholds returns the configured warning times for a session type, in seconds, largest first
+func warningThresholds(sessionType SessionType) []int {
+	minutes := DefaultSettings.WarningMinutes[sessionType]
+	seconds := make([]int, 0, len(minutes))
+	for _, m := range minutes {
+		if m > 0 {
+			seconds = append(seconds, m*60)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(seconds)))
+	return seconds
+}
+
+// hasFiredWarning reports whether the warning at this threshold already went off
+func (s *SessionSlot) hasFiredWarning(threshold int) bool {
+	for _, fired := range s.WarningsFired {
+		if fired == threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// dueWarning finds the warning to fire now, if any, and marks every threshold
+// already passed as fired. Thresholds longer than the session never fire, and
+// because WarningsFired is saved with the session they survive pause and restart.
+func dueWarning(session *SessionSlot, remaining int, thresholds []int) (int, bool) {
+	if remaining <= 0 {
+		return 0, false
+	}
+
+	due := 0
+	found := false
+	for _, threshold := range thresholds {
+		if threshold >= session.Duration || remaining > threshold || session.hasFiredWarning(threshold) {
+			continue
+		}
+		session.WarningsFired = append(session.WarningsFired, threshold)
+		// Thresholds are largest first, so the last one passed is the closest
+		due = threshold
+		found = true
+	}
+	return due, found
+}
+
+// checkSessionWarnings fires a heads-up alert when a warning threshold is reached
+func checkSessionWarnings() {
+	current := sessionManager.GetCurrentSession()
+	if current == nil {
+		return
+	}
+
+	threshold, ok := dueWarning(current, timeRemaining, warningThresholds(current.Type))
+	if !ok {
+		return
+	}
+
+	alert := Alert{
+		Event:   EventSessionWarning,
+		Session: *current,
+		Title:   "⏳ Nearly there!",
+		Message: fmt.Sprintf("%s left in %s - time to wrap up, matey!",
+			formatWarningTime(threshold), current.GetSessionLabel()),
+	}
+	dispatchAlert(alert, alertRoutes(), builtinNotifiers)
+}
+
+// formatWarningTime turns a threshold into "5 minutes" / "1 minute"
+func formatWarningTime(seconds int) string {
+	minutes := seconds / 60
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
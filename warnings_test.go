@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestWarningThresholds(t *testing.T) {
+	originalSettings := DefaultSettings
+	defer func() {
+		DefaultSettings = originalSettings
+	}()
+
+	DefaultSettings.WarningMinutes = map[SessionType][]int{
+		SessionWork: {1, 5, 0},
+	}
+
+	got := warningThresholds(SessionWork)
+	if len(got) != 2 || got[0] != 300 || got[1] != 60 {
+		t.Errorf("Expected [300 60], got %v", got)
+	}
+	if len(warningThresholds(SessionShortBreak)) != 0 {
+		t.Error("Unconfigured session types should have no warnings")
+	}
+}
+
+func TestDueWarningFiresOnce(t *testing.T) {
+	session := &SessionSlot{Type: SessionWork, Duration: 25 * 60}
+	thresholds := []int{300, 60}
+
+	if _, ok := dueWarning(session, 301, thresholds); ok {
+		t.Error("No warning due before the threshold")
+	}
+	if threshold, ok := dueWarning(session, 300, thresholds); !ok || threshold != 300 {
+		t.Errorf("Expected 5 minute warning, got %d %v", threshold, ok)
+	}
+
+	// Pausing and resuming leaves the time below the threshold - no repeat
+	if _, ok := dueWarning(session, 299, thresholds); ok {
+		t.Error("5 minute warning should only fire once")
+	}
+	if threshold, ok := dueWarning(session, 60, thresholds); !ok || threshold != 60 {
+		t.Errorf("Expected 1 minute warning, got %d %v", threshold, ok)
+	}
+}
+
+func TestDueWarningAfterRestore(t *testing.T) {
+	sm := newTestSessionManager(t, 2)
+	sm.Sessions[0].WarningsFired = []int{300}
+
+	// Round-trip through JSON like a restart does
+	data, err := sm.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &SessionManager{}
+	if err := restored.FromJSON(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := dueWarning(restored.GetCurrentSession(), 200, []int{300}); ok {
+		t.Error("Warning fired before the restart should not fire again")
+	}
+}
+
+func TestDueWarningSkipsJumpsAndShortSessions(t *testing.T) {
+	// Restored well past both thresholds: one alert for the closest, both marked
+	session := &SessionSlot{Type: SessionWork, Duration: 25 * 60}
+	if threshold, ok := dueWarning(session, 30, []int{300, 60}); !ok || threshold != 60 {
+		t.Errorf("Expected the 1 minute warning, got %d %v", threshold, ok)
+	}
+	if len(session.WarningsFired) != 2 {
+		t.Errorf("Both passed thresholds should be marked, got %v", session.WarningsFired)
+	}
+
+	// A 4 minute break never gets a 5 minute warning
+	short := &SessionSlot{Type: SessionShortBreak, Duration: 4 * 60}
+	if _, ok := dueWarning(short, 240, []int{300}); ok {
+		t.Error("Threshold longer than the session should not fire")
+	}
+}
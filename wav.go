@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// wavAudio is decoded PCM from a RIFF/WAVE file
+type wavAudio struct {
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	Samples       []byte // Raw little-endian PCM frames
+}
+
+// errUnsupportedWAV is returned for WAV files we can't handle (non-PCM, odd bit depths)
+var errUnsupportedWAV = errors.New("unsupported WAV file (only 8/16-bit PCM)")
+
+// decodeWAV parses a PCM WAV file
+func decodeWAV(data []byte) (*wavAudio, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	audio := &wavAudio{}
+	haveFormat := false
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkID := string(data[pos : pos+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		if chunkSize < 0 || pos+chunkSize > len(data) {
+			return nil, errors.New("truncated WAV chunk")
+		}
+		chunk := data[pos : pos+chunkSize]
+
+		switch chunkID {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, errors.New("short WAV format chunk")
+			}
+			if binary.LittleEndian.Uint16(chunk[0:2]) != 1 {
+				return nil, errUnsupportedWAV
+			}
+			audio.Channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			audio.SampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			audio.BitsPerSample = int(binary.LittleEndian.Uint16(chunk[14:16]))
+			if audio.BitsPerSample != 8 && audio.BitsPerSample != 16 {
+				return nil, errUnsupportedWAV
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, errors.New("WAV data before format")
+			}
+			audio.Samples = chunk
+			return audio, nil
+		}
+
+		// Chunks are padded to an even size
+		pos += chunkSize + chunkSize%2
+	}
+	return nil, errors.New("WAV file has no data")
+}
+
+// encode writes the audio back out as a WAV file
+func (a *wavAudio) encode() []byte {
+	blockAlign := a.Channels * a.BitsPerSample / 8
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(a.Samples)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(a.Channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(a.SampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(a.SampleRate*blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(a.BitsPerSample))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(a.Samples)))
+	buf.Write(a.Samples)
+	return buf.Bytes()
+}
+
+// withVolume returns a copy with every sample scaled (0.0 = silent, 1.0 = unchanged)
+func (a *wavAudio) withVolume(volume float64) *wavAudio {
+	volume = math.Max(0, math.Min(1, volume))
+	scaled := *a
+	scaled.Samples = make([]byte, len(a.Samples))
+
+	switch a.BitsPerSample {
+	case 8:
+		// 8-bit WAV is unsigned, centred on 128
+		for i, sample := range a.Samples {
+			scaled.Samples[i] = byte(128 + (float64(sample)-128)*volume)
+		}
+	case 16:
+		for i := 0; i+1 < len(a.Samples); i += 2 {
+			sample := int16(binary.LittleEndian.Uint16(a.Samples[i:]))
+			binary.LittleEndian.PutUint16(scaled.Samples[i:], uint16(int16(float64(sample)*volume)))
+		}
+	}
+	return &scaled
+}
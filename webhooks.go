@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Webhook delivery tuning
+const (
+	webhookMaxAttempts   = 4               // Tries per delivery round before waiting for the next round
+	webhookBaseBackoff   = 2 * time.Second // Doubled after every failed try
+	webhookRetryInterval = time.Minute     // How often queued deliveries are retried while offline
+	webhookTimeout       = 10 * time.Second
+	webhookGiveUpTries   = 200            // Total tries before a delivery is dropped
+	webhookMaxAge        = 24 * time.Hour // How long a delivery may wait before it's dropped
+)
+
+// Webhook is a URL that gets session events POSTed to it
+type Webhook struct {
+	URL    string           `json:"url"`
+	Events []LifecycleEvent `json:"events,omitempty"` // Empty = session_start and session_finish
+	Secret string           `json:"secret,omitempty"` // Signs the body with HMAC-SHA256
+}
+
+// defaultWebhookEvents are sent when a webhook doesn't list its own
+var defaultWebhookEvents = []LifecycleEvent{LifecycleSessionStart, LifecycleSessionFinish}
+
+// wants reports whether the webhook subscribes to an event
+func (w Webhook) wants(event LifecycleEvent) bool {
+	events := w.Events
+	if len(events) == 0 {
+		events = defaultWebhookEvents
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookDelivery is one queued POST. The secret is looked up again at send
+// time so it never ends up in the queue file.
+type webhookDelivery struct {
+	ID        string          `json:"id"`
+	URL       string          `json:"url"`
+	Event     LifecycleEvent  `json:"event"`
+	Body      json.RawMessage `json:"body"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WebhookDispatcher delivers webhooks in order (per URL) and keeps undelivered ones on disk
+type WebhookDispatcher struct {
+	client      *http.Client
+	queuePath   string
+	baseBackoff time.Duration
+	retryEvery  time.Duration
+	giveUpTries int              // Total tries before a delivery is dropped
+	maxAge      time.Duration    // Age after which an undelivered delivery is dropped
+	webhooks    func() []Webhook // Current config, so edits apply to queued deliveries
+
+	mu      sync.Mutex
+	pending []webhookDelivery
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Global webhook dispatcher (nil until setupWebhooks)
+var webhookDispatcher *WebhookDispatcher
+
+// NewWebhookDispatcher loads any queued deliveries and starts the sender
+func NewWebhookDispatcher(queuePath string, webhooks func() []Webhook) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		client:      &http.Client{Timeout: webhookTimeout},
+		queuePath:   queuePath,
+		baseBackoff: webhookBaseBackoff,
+		retryEvery:  webhookRetryInterval,
+		giveUpTries: webhookGiveUpTries,
+		maxAge:      webhookMaxAge,
+		webhooks:    webhooks,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	d.loadQueue()
+	go d.run()
+	return d
+}
+
+// setupWebhooks starts the dispatcher and subscribes it to lifecycle events
+func setupWebhooks() {
+	webhookDispatcher = NewWebhookDispatcher(
+		filepath.Join(getProfileDir(), "webhook_queue.json"),
+		func() []Webhook { return DefaultSettings.Webhooks },
+	)
+	onLifecycleEvent(webhookDispatcher.HandleEvent)
+}
+
+// HandleEvent queues a delivery for every webhook that wants the event
+func (d *WebhookDispatcher) HandleEvent(sessionEvent SessionEvent) {
+	body, err := json.Marshal(sessionEvent)
+	if err != nil {
+		log.Printf("webhooks: %v", err)
+		return
+	}
+	for _, webhook := range d.webhooks() {
+		if webhook.URL == "" || !webhook.wants(sessionEvent.Event) {
+			continue
+		}
+		d.Enqueue(webhookDelivery{
+			ID:        newDeliveryID(),
+			URL:       webhook.URL,
+			Event:     sessionEvent.Event,
+			Body:      body,
+			CreatedAt: time.Now(),
+		})
+	}
+}
+
+// Enqueue adds a delivery, saves the queue and nudges the sender
+func (d *WebhookDispatcher) Enqueue(delivery webhookDelivery) {
+	d.mu.Lock()
+	d.pending = append(d.pending, delivery)
+	d.saveQueueLocked()
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default: // Already awake
+	}
+}
+
+// Pending returns how many deliveries are waiting
+func (d *WebhookDispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+// Stop shuts the sender down; queued deliveries stay on disk for next time
+func (d *WebhookDispatcher) Stop() {
+	close(d.stop)
+	<-d.done
+}
+
+// run sends queued deliveries whenever woken, and periodically while any are left
+func (d *WebhookDispatcher) run() {
+	defer close(d.done)
+	retry := time.NewTicker(d.retryEvery)
+	defer retry.Stop()
+
+	for {
+		d.flush()
+		select {
+		case <-d.wake:
+		case <-retry.C:
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// flush delivers the queue in order per URL. A URL that can't be reached
+// keeps its deliveries for the next round without holding up the others.
+func (d *WebhookDispatcher) flush() {
+	waiting := map[string]bool{} // URLs that failed this round
+	for {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+
+		delivery, ok := d.nextDelivery(waiting)
+		if !ok {
+			return
+		}
+		if d.expired(delivery) {
+			d.drop(delivery)
+			continue
+		}
+
+		delivered, permanent := d.deliverWithRetries(&delivery)
+		switch {
+		case delivered || permanent:
+			d.remove(delivery.ID)
+		case d.expired(delivery):
+			d.drop(delivery)
+		default:
+			// Probably offline - keep it (and the rest for its URL) for the next round
+			waiting[delivery.URL] = true
+			d.mu.Lock()
+			for i := range d.pending {
+				if d.pending[i].ID == delivery.ID {
+					d.pending[i].Attempts = delivery.Attempts
+					d.saveQueueLocked()
+					break
+				}
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// nextDelivery is the oldest delivery whose URL hasn't failed this round
+func (d *WebhookDispatcher) nextDelivery(waiting map[string]bool) (webhookDelivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, delivery := range d.pending {
+		if !waiting[delivery.URL] {
+			return delivery, true
+		}
+	}
+	return webhookDelivery{}, false
+}
+
+// expired reports whether a delivery has been tried too often or waited too long
+func (d *WebhookDispatcher) expired(delivery webhookDelivery) bool {
+	return (d.giveUpTries > 0 && delivery.Attempts >= d.giveUpTries) ||
+		(d.maxAge > 0 && time.Since(delivery.CreatedAt) > d.maxAge)
+}
+
+// drop gives up on a delivery
+func (d *WebhookDispatcher) drop(delivery webhookDelivery) {
+	log.Printf("webhooks: giving up on %s delivery to %s after %d tries since %s",
+		delivery.Event, delivery.URL, delivery.Attempts, delivery.CreatedAt.Format(time.RFC3339))
+	d.remove(delivery.ID)
+}
+
+// remove takes a delivery off the queue
+func (d *WebhookDispatcher) remove(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.pending {
+		if d.pending[i].ID == id {
+			d.pending = append(d.pending[:i], d.pending[i+1:]...)
+			d.saveQueueLocked()
+			return
+		}
+	}
+}
+
+// deliverWithRetries tries a delivery a few times with exponential backoff.
+// permanent is true if the receiver rejected it and it should be dropped.
+func (d *WebhookDispatcher) deliverWithRetries(delivery *webhookDelivery) (delivered bool, permanent bool) {
+	backoff := d.baseBackoff
+	for attempt := 0; attempt < webhookMaxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-d.stop:
+				return false, false
+			}
+			backoff *= 2
+		}
+
+		delivery.Attempts++
+		err := d.send(*delivery)
+		if err == nil {
+			return true, false
+		}
+		if rejected, ok := err.(*webhookRejectedError); ok {
+			log.Printf("webhooks: dropping %s delivery to %s: %v", delivery.Event, delivery.URL, rejected)
+			return false, true
+		}
+		log.Printf("webhooks: %s delivery to %s failed (attempt %d): %v", delivery.Event, delivery.URL, delivery.Attempts, err)
+	}
+	return false, false
+}
+
+// webhookRejectedError is a failure that retrying won't fix (4xx, bad URL, removed webhook)
+type webhookRejectedError struct {
+	reason string
+}
+
+func (e *webhookRejectedError) Error() string {
+	return e.reason
+}
+
+// send POSTs one delivery, signing it if the webhook has a secret
+func (d *WebhookDispatcher) send(delivery webhookDelivery) error {
+	secret := ""
+	found := false
+	for _, webhook := range d.webhooks() {
+		if webhook.URL == delivery.URL {
+			secret = webhook.Secret
+			found = true
+			break
+		}
+	}
+	if !found {
+		return &webhookRejectedError{reason: "webhook no longer configured"}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return &webhookRejectedError{reason: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoModoro")
+	req.Header.Set("X-GoModoro-Event", string(delivery.Event))
+	req.Header.Set("X-GoModoro-Delivery", delivery.ID)
+	if secret != "" {
+		req.Header.Set("X-GoModoro-Signature", signWebhookBody(secret, delivery.Body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return &webhookRejectedError{reason: fmt.Sprintf("receiver rejected it with HTTP %d", resp.StatusCode)}
+	default:
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+}
+
+// signWebhookBody returns the X-GoModoro-Signature header value
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryID returns a random ID receivers can use to drop duplicates
+func newDeliveryID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// loadQueue restores deliveries that didn't make it out last time
+func (d *WebhookDispatcher) loadQueue() {
+	data, err := os.ReadFile(d.queuePath)
+	if err != nil {
+		return // No queue yet
+	}
+	if err := json.Unmarshal(data, &d.pending); err != nil {
+		log.Printf("webhooks: ignoring unreadable queue %s: %v", d.queuePath, err)
+		d.pending = nil
+	}
+}
+
+// saveQueueLocked writes the queue to disk in one step, so a crash never
+// leaves half of it (caller holds d.mu)
+func (d *WebhookDispatcher) saveQueueLocked() {
+	if len(d.pending) == 0 {
+		os.Remove(d.queuePath)
+		return
+	}
+	data, err := json.MarshalIndent(d.pending, "", "  ")
+	if err != nil {
+		log.Printf("webhooks: %v", err)
+		return
+	}
+	if err := writeFileAtomic(d.queuePath, data, 0600); err != nil {
+		log.Printf("webhooks: failed to save queue: %v", err)
+	}
+}
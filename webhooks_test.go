@@ -0,0 +1,217 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is a local httptest endpoint that records what it gets
+type webhookReceiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	bodies   []string
+	headers  []http.Header
+	failNext int // Answer 500 this many times first
+	status   int // Status for accepted requests (default 204)
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	t.Helper()
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		if receiver.failNext > 0 {
+			receiver.failNext--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		receiver.bodies = append(receiver.bodies, string(body))
+		receiver.headers = append(receiver.headers, r.Header.Clone())
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.server.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+// newTestDispatcher makes a dispatcher with tiny backoffs
+func newTestDispatcher(t *testing.T, queuePath string, webhooks []Webhook) *WebhookDispatcher {
+	t.Helper()
+	d := &WebhookDispatcher{
+		client:      &http.Client{Timeout: time.Second},
+		queuePath:   queuePath,
+		baseBackoff: 10 * time.Millisecond,
+		retryEvery:  50 * time.Millisecond,
+		giveUpTries: webhookGiveUpTries,
+		maxAge:      webhookMaxAge,
+		webhooks:    func() []Webhook { return webhooks },
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	d.loadQueue()
+	go d.run()
+	return d
+}
+
+// waitFor polls until cond is true or fails the test
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+func TestWebhookSignedDeliveryWithEventFilter(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	webhooks := []Webhook{
+		{URL: receiver.server.URL, Secret: "shh"},
+		{URL: receiver.server.URL + "/pauses", Events: []LifecycleEvent{LifecycleSessionPause}},
+	}
+	d := newTestDispatcher(t, filepath.Join(t.TempDir(), "queue.json"), webhooks)
+	defer d.Stop()
+
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionStart, SessionType: SessionWork})
+	waitFor(t, "delivery", func() bool { return receiver.received() == 1 })
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	header := receiver.headers[0]
+	if header.Get("X-GoModoro-Event") != string(LifecycleSessionStart) {
+		t.Errorf("Unexpected event header %q", header.Get("X-GoModoro-Event"))
+	}
+	expected := signWebhookBody("shh", []byte(receiver.bodies[0]))
+	if header.Get("X-GoModoro-Signature") != expected {
+		t.Errorf("Bad signature %q, want %q", header.Get("X-GoModoro-Signature"), expected)
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	receiver.failNext = 2
+	d := newTestDispatcher(t, filepath.Join(t.TempDir(), "queue.json"), []Webhook{{URL: receiver.server.URL}})
+	defer d.Stop()
+
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionFinish})
+	waitFor(t, "delivery after retries", func() bool { return receiver.received() == 1 })
+	waitFor(t, "empty queue", func() bool { return d.Pending() == 0 })
+}
+
+func TestWebhookRejectedIsDropped(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	receiver.status = http.StatusBadRequest
+	d := newTestDispatcher(t, filepath.Join(t.TempDir(), "queue.json"), []Webhook{{URL: receiver.server.URL}})
+	defer d.Stop()
+
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionStart})
+	waitFor(t, "rejected delivery to be dropped", func() bool {
+		return receiver.received() == 1 && d.Pending() == 0
+	})
+}
+
+func TestWebhookQueueSurvivesOffline(t *testing.T) {
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+
+	// Grab a free port, then close it so deliveries fail like we're offline
+	offline := httptest.NewServer(http.NotFoundHandler())
+	url := offline.URL
+	offline.Close()
+
+	webhooks := []Webhook{{URL: url}}
+	d := newTestDispatcher(t, queuePath, webhooks)
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionStart})
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionFinish})
+	time.Sleep(100 * time.Millisecond)
+	d.Stop()
+
+	if d.Pending() != 2 {
+		t.Fatalf("Expected 2 queued deliveries while offline, got %d", d.Pending())
+	}
+
+	// "Restart" with the receiver back on a new address
+	receiver := newWebhookReceiver(t)
+	restarted := &WebhookDispatcher{queuePath: queuePath}
+	restarted.loadQueue()
+	for i := range restarted.pending {
+		restarted.pending[i].URL = receiver.server.URL
+	}
+	restarted.saveQueueLocked()
+
+	d2 := newTestDispatcher(t, queuePath, []Webhook{{URL: receiver.server.URL}})
+	defer d2.Stop()
+	waitFor(t, "queued deliveries", func() bool { return receiver.received() == 2 })
+	waitFor(t, "empty queue", func() bool { return d2.Pending() == 0 })
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if !strings.Contains(receiver.bodies[0], string(LifecycleSessionStart)) ||
+		!strings.Contains(receiver.bodies[1], string(LifecycleSessionFinish)) {
+		t.Errorf("Queued deliveries should arrive in order, got %v", receiver.bodies)
+	}
+}
+
+// deadWebhookURL is an address nothing listens on, like a receiver that's gone
+func deadWebhookURL() string {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	return dead.URL
+}
+
+func TestWebhookDeadURLDoesNotBlockOthers(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	webhooks := []Webhook{{URL: deadWebhookURL()}, {URL: receiver.server.URL}}
+	d := newTestDispatcher(t, filepath.Join(t.TempDir(), "queue.json"), webhooks)
+	defer d.Stop()
+
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionStart})
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionFinish})
+	// Delivered ones leave the queue once the receiver has answered; the dead
+	// URL's 2 stay queued
+	waitFor(t, "live webhook deliveries", func() bool { return receiver.received() == 2 && d.Pending() == 2 })
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if !strings.Contains(receiver.bodies[0], string(LifecycleSessionStart)) ||
+		!strings.Contains(receiver.bodies[1], string(LifecycleSessionFinish)) {
+		t.Errorf("Deliveries should still arrive in order, got %v", receiver.bodies)
+	}
+}
+
+func TestWebhookGivesUpEventually(t *testing.T) {
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+	url := deadWebhookURL()
+
+	// Too many tries
+	d := newTestDispatcher(t, queuePath, []Webhook{{URL: url}})
+	d.giveUpTries = webhookMaxAttempts * 2
+	d.HandleEvent(SessionEvent{Event: LifecycleSessionStart})
+	waitFor(t, "delivery to be dropped after its tries", func() bool { return d.Pending() == 0 })
+	d.Stop()
+
+	// Too old (e.g. queued before a long trip offline)
+	d2 := newTestDispatcher(t, queuePath, []Webhook{{URL: url}})
+	defer d2.Stop()
+	d2.Enqueue(webhookDelivery{ID: "old", URL: url, Event: LifecycleSessionFinish, Body: []byte("{}"),
+		CreatedAt: time.Now().Add(-2 * webhookMaxAge)})
+	waitFor(t, "old delivery to be dropped", func() bool { return d2.Pending() == 0 })
+}